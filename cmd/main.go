@@ -1,15 +1,28 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/brpaz/raindrop-images-dl/internal/app"
+	"github.com/brpaz/raindrop-images-dl/internal/cmd"
 )
 
 func main() {
+	// Stop gracefully on Ctrl+C or SIGTERM, ex: when the container stops. A second signal exits right away.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+
 	app := app.New()
 
-	if err := app.Run(); err != nil {
-		log.Fatal(err)
+	err := app.Run(ctx)
+	stop()
+
+	if err != nil {
+		log.Print(err)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
@@ -1,6 +1,8 @@
 package app
 
 import (
+	"context"
+
 	"github.com/spf13/cobra"
 
 	"github.com/brpaz/raindrop-images-dl/internal/cmd"
@@ -31,14 +33,18 @@ func (a *App) registerCommands() {
 	})
 
 	downloadCmd := cmd.NewDownloadCmd()
+	configCmd := cmd.NewConfigCmd()
+	loginCmd := cmd.NewLoginCmd()
 
 	a.rootCmd.AddCommand(
 		versionCmd,
 		downloadCmd,
+		configCmd,
+		loginCmd,
 	)
 }
 
-// Run executes the CLI application.
-func (a *App) Run() error {
-	return a.rootCmd.Execute()
+// Run executes the CLI application. Cancelling the context stops the running command, ex: on SIGTERM.
+func (a *App) Run(ctx context.Context) error {
+	return a.rootCmd.ExecuteContext(ctx)
 }
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	FlagDownloadApiKeyFile    = "api-key-file"
+	FlagDownloadApiKeyCommand = "api-key-command"
+)
+
+// stdinFile is the API key file name that reads the key from the standard input
+const stdinFile = "-"
+
+var errEmptyAPIKey = errors.New("the API key is empty")
+
+// resolveAPIKey sets the API key flag from the API key file or command, when one of them is used instead of the key itself.
+// The key is never included in the errors, so that it does not end up in logs.
+func resolveAPIKey(cmd *cobra.Command) error {
+	apiKey, _ := cmd.Flags().GetString(FlagDownloadApiKey)
+	file, _ := cmd.Flags().GetString(FlagDownloadApiKeyFile)
+	command, _ := cmd.Flags().GetString(FlagDownloadApiKeyCommand)
+
+	sources := 0
+	for _, source := range []string{apiKey, file, command} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return fmt.Errorf("only one of --%s, --%s and --%s can be used", FlagDownloadApiKey, FlagDownloadApiKeyFile, FlagDownloadApiKeyCommand)
+	}
+
+	var key string
+	var err error
+
+	switch {
+	case file != "":
+		key, err = readAPIKeyFile(cmd.InOrStdin(), file)
+	case command != "":
+		key, err = runAPIKeyCommand(cmd.Context(), command)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return cmd.Flags().Set(FlagDownloadApiKey, key)
+}
+
+// readAPIKeyFile reads the API key from a file, like a Docker or Kubernetes secret, or from stdin when the file is "-"
+func readAPIKeyFile(stdin io.Reader, file string) (string, error) {
+	var data []byte
+	var err error
+
+	if file == stdinFile {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(file) // #nosec
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read the API key file: %w", err)
+	}
+
+	key := parseAPIKey(data)
+	if key == "" {
+		return "", fmt.Errorf("%w, check the API key file %s", errEmptyAPIKey, file)
+	}
+
+	return key, nil
+}
+
+// runAPIKeyCommand runs a command with the system shell, like "pass show raindrop", and reads the API key from its output
+func runAPIKeyCommand(ctx context.Context, command string) (string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+
+	var stdout, stderr bytes.Buffer
+	c := exec.CommandContext(ctx, shell, flag, command) // #nosec
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+
+	if err := c.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("the API key command failed: %w: %s", err, msg)
+		}
+		return "", fmt.Errorf("the API key command failed: %w", err)
+	}
+
+	key := parseAPIKey(stdout.Bytes())
+	if key == "" {
+		return "", fmt.Errorf("%w, check the output of the API key command", errEmptyAPIKey)
+	}
+
+	return key, nil
+}
+
+// parseAPIKey returns the first line of the data, as password managers may print other fields on the following lines
+func parseAPIKey(data []byte) string {
+	line, _, _ := strings.Cut(string(data), "\n")
+	return strings.TrimSpace(line)
+}
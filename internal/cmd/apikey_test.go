@@ -0,0 +1,112 @@
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKeyFile writes an API key file, like a Docker secret, returning its path
+func writeKeyFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "raindrop_api_key")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestDownloadPreFn_APIKey(t *testing.T) {
+	t.Run("reads the API key from a file", func(t *testing.T) {
+		resetEnv(t)
+		downloadCmd := setupTestDownloadCmd()
+		require.NoError(t, downloadCmd.Flags().Set("api-key-file", writeKeyFile(t, "file-key\n")))
+
+		require.NoError(t, downloadCmd.PreRunE(downloadCmd, []string{}))
+
+		apiKey, _ := downloadCmd.Flags().GetString("api-key")
+		assert.Equal(t, "file-key", apiKey)
+	})
+
+	t.Run("reads the API key file from the environment", func(t *testing.T) {
+		resetEnv(t)
+		t.Setenv("RAINDROP_API_KEY_FILE", writeKeyFile(t, "file-key"))
+
+		downloadCmd := setupTestDownloadCmd()
+		require.NoError(t, downloadCmd.PreRunE(downloadCmd, []string{}))
+
+		apiKey, _ := downloadCmd.Flags().GetString("api-key")
+		assert.Equal(t, "file-key", apiKey)
+	})
+
+	t.Run("prefers the API key file flag over the API key environment variable", func(t *testing.T) {
+		resetEnv(t)
+		t.Setenv("RAINDROP_API_KEY", "env-key")
+
+		downloadCmd := setupTestDownloadCmd()
+		require.NoError(t, downloadCmd.Flags().Set("api-key-file", writeKeyFile(t, "file-key")))
+
+		require.NoError(t, downloadCmd.PreRunE(downloadCmd, []string{}))
+
+		apiKey, _ := downloadCmd.Flags().GetString("api-key")
+		assert.Equal(t, "file-key", apiKey)
+	})
+
+	t.Run("reads the API key from stdin", func(t *testing.T) {
+		resetEnv(t)
+		downloadCmd := setupTestDownloadCmd()
+		downloadCmd.SetIn(strings.NewReader("stdin-key\n"))
+		require.NoError(t, downloadCmd.Flags().Set("api-key-file", "-"))
+
+		require.NoError(t, downloadCmd.PreRunE(downloadCmd, []string{}))
+
+		apiKey, _ := downloadCmd.Flags().GetString("api-key")
+		assert.Equal(t, "stdin-key", apiKey)
+	})
+
+	t.Run("reads the API key from the first line of a command output", func(t *testing.T) {
+		resetEnv(t)
+		downloadCmd := setupTestDownloadCmd()
+		require.NoError(t, downloadCmd.Flags().Set("api-key-command", "printf 'command-key\\nusername: me\\n'"))
+
+		require.NoError(t, downloadCmd.PreRunE(downloadCmd, []string{}))
+
+		apiKey, _ := downloadCmd.Flags().GetString("api-key")
+		assert.Equal(t, "command-key", apiKey)
+	})
+
+	t.Run("returns error when the command fails", func(t *testing.T) {
+		resetEnv(t)
+		downloadCmd := setupTestDownloadCmd()
+		require.NoError(t, downloadCmd.Flags().Set("api-key-command", "echo 'not logged in' >&2; exit 3"))
+
+		err := downloadCmd.PreRunE(downloadCmd, []string{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "the API key command failed")
+		assert.Contains(t, err.Error(), "not logged in")
+	})
+
+	t.Run("returns error when the API key file is empty", func(t *testing.T) {
+		resetEnv(t)
+		downloadCmd := setupTestDownloadCmd()
+		require.NoError(t, downloadCmd.Flags().Set("api-key-file", writeKeyFile(t, "\n")))
+
+		err := downloadCmd.PreRunE(downloadCmd, []string{})
+		assert.ErrorContains(t, err, "the API key is empty")
+	})
+
+	t.Run("returns error when several API key sources are passed", func(t *testing.T) {
+		resetEnv(t)
+		downloadCmd := setupTestDownloadCmd()
+		require.NoError(t, downloadCmd.Flags().Set("api-key", "flag-key"))
+		require.NoError(t, downloadCmd.Flags().Set("api-key-file", writeKeyFile(t, "file-key")))
+
+		err := downloadCmd.PreRunE(downloadCmd, []string{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "only one of --api-key, --api-key-file and --api-key-command can be used")
+		assert.NotContains(t, err.Error(), "flag-key")
+	})
+}
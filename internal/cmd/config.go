@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+
+	"github.com/brpaz/raindrop-images-dl/internal/config"
+)
+
+const (
+	FlagConfig  = "config"
+	FlagProfile = "profile"
+)
+
+// redacted replaces secrets in the printed configuration
+const redacted = "<redacted>"
+
+// setting binds a download flag to its environment variable, if any, and to its field in the config profiles
+type setting struct {
+	flag string
+	env  string
+	// secret settings are redacted, and their values are not included in errors
+	secret bool
+	// field returns a pointer to the profile field: *string, *int, **bool or *[]string
+	field func(p *config.Profile) any
+}
+
+// settings are the download flags that can be set from the environment or a profile.
+// Values are resolved in this order of precedence: flag > environment variable > profile > default.
+var settings = []setting{
+	{flag: FlagDownloadApiKey, env: "RAINDROP_API_KEY", secret: true, field: func(p *config.Profile) any { return &p.APIKey }},
+	{flag: FlagDownloadApiKeyFile, env: "RAINDROP_API_KEY_FILE", field: func(p *config.Profile) any { return &p.APIKeyFile }},
+	{flag: FlagDownloadApiKeyCommand, env: "RAINDROP_API_KEY_COMMAND", field: func(p *config.Profile) any { return &p.APIKeyCommand }},
+	{flag: FlagDownloadCollection, env: "RAINDROP_COLLECTION", field: func(p *config.Profile) any { return &p.Collection }},
+	{flag: FlagDownloadAll, field: func(p *config.Profile) any { return &p.All }},
+	{flag: FlagDownloadRecursive, field: func(p *config.Profile) any { return &p.Recursive }},
+	{flag: FlagDownloadInclude, field: func(p *config.Profile) any { return &p.Include }},
+	{flag: FlagDownloadExclude, field: func(p *config.Profile) any { return &p.Exclude }},
+	{flag: FlagDownloadOutput, env: "OUTPUT_DIR", field: func(p *config.Profile) any { return &p.Output }},
+	{flag: FlagDownloadGenInfo, env: "GEN_INFO_JSON", field: func(p *config.Profile) any { return &p.GenInfoJSON }},
+	{flag: FlagDownloadNameTmpl, field: func(p *config.Profile) any { return &p.NameTemplate }},
+	{flag: FlagDownloadSource, field: func(p *config.Profile) any { return &p.Source }},
+	{flag: FlagDownloadAllMedia, field: func(p *config.Profile) any { return &p.AllMedia }},
+	{flag: FlagDownloadConcurrency, field: func(p *config.Profile) any { return &p.Concurrency }},
+	{flag: FlagDownloadMirror, field: func(p *config.Profile) any { return &p.Mirror }},
+	{flag: FlagDownloadPrune, field: func(p *config.Profile) any { return &p.Prune }},
+	{flag: FlagDownloadTypes, field: func(p *config.Profile) any { return &p.Types }},
+	{flag: FlagDownloadQuery, field: func(p *config.Profile) any { return &p.Query }},
+	{flag: FlagDownloadTag, field: func(p *config.Profile) any { return &p.Tags }},
+	{flag: FlagDownloadDomain, field: func(p *config.Profile) any { return &p.Domain }},
+	{flag: FlagDownloadSince, field: func(p *config.Profile) any { return &p.Since }},
+	{flag: FlagDownloadUntil, field: func(p *config.Profile) any { return &p.Until }},
+	{flag: FlagDownloadProgress, field: func(p *config.Profile) any { return &p.Progress }},
+	{flag: FlagDownloadReport, field: func(p *config.Profile) any { return &p.Report }},
+	{flag: FlagDownloadWatch, env: "RAINDROP_WATCH", field: func(p *config.Profile) any { return &p.Watch }},
+	{flag: FlagDownloadInterval, env: "RAINDROP_INTERVAL", field: func(p *config.Profile) any { return &p.Interval }},
+	{flag: FlagDownloadSchedule, env: "RAINDROP_SCHEDULE", field: func(p *config.Profile) any { return &p.Schedule }},
+	{flag: FlagDownloadMetricsAddr, env: "RAINDROP_METRICS_ADDR", field: func(p *config.Profile) any { return &p.MetricsAddr }},
+}
+
+// exclusiveSettings are groups of flags that can not be used together. Once a flag of a group is set,
+// the other flags of the group are not read from lower precedence sources.
+var exclusiveSettings = [][]string{
+	{FlagDownloadCollection, FlagDownloadAll},
+	{FlagDownloadApiKey, FlagDownloadApiKeyFile, FlagDownloadApiKeyCommand},
+	{FlagDownloadInterval, FlagDownloadSchedule},
+}
+
+// addConfigFlags adds the flags that select the config file and profile
+func addConfigFlags(cmd *cobra.Command) {
+	cmd.Flags().String(FlagConfig, "", "The config file, defaults to raindrop-images-dl/config.yaml in the user config directory (env: RAINDROP_CONFIG)")
+	cmd.Flags().String(FlagProfile, "", "The config profile to use, defaults to the default_profile of the config file (env: RAINDROP_PROFILE)")
+}
+
+// loadProfile loads the config file and the profile selected by the flags or the environment
+func loadProfile(cmd *cobra.Command) (*config.Config, config.Profile, string, error) {
+	path := flagOrEnv(cmd, FlagConfig, "RAINDROP_CONFIG")
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, config.Profile{}, "", err
+	}
+
+	profile, name, err := cfg.Profile(flagOrEnv(cmd, FlagProfile, "RAINDROP_PROFILE"))
+	if err != nil {
+		return nil, config.Profile{}, "", err
+	}
+
+	return cfg, profile, name, nil
+}
+
+// flagOrEnv returns the value of a string flag, or of the environment variable when the flag is not set
+func flagOrEnv(cmd *cobra.Command, flag, env string) string {
+	if value, _ := cmd.Flags().GetString(flag); value != "" {
+		return value
+	}
+	return os.Getenv(env)
+}
+
+// applySettings sets the download flags that were not passed from the environment, then from the profile,
+// so that the flags hold the effective configuration.
+func applySettings(cmd *cobra.Command, profile config.Profile) error {
+	flags := cmd.Flags()
+
+	for _, s := range settings {
+		if s.env == "" || isSettingSet(flags, s.flag) {
+			continue
+		}
+
+		if value := os.Getenv(s.env); value != "" {
+			if err := flags.Set(s.flag, value); err != nil {
+				return fmt.Errorf("invalid value %s of the %s environment variable: %w", s.display(value), s.env, err)
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if isSettingSet(flags, s.flag) {
+			continue
+		}
+
+		values, ok := profileValue(s.field(&profile))
+		if !ok {
+			continue
+		}
+
+		if err := setFlag(flags, s.flag, values); err != nil {
+			return fmt.Errorf("invalid %s value %s in the config profile: %w", s.flag, s.display(strings.Join(values, ",")), err)
+		}
+	}
+
+	return nil
+}
+
+// isSettingSet reports whether a flag, or another flag of its exclusive group, was already set
+func isSettingSet(flags *pflag.FlagSet, name string) bool {
+	if flags.Changed(name) {
+		return true
+	}
+
+	for _, group := range exclusiveSettings {
+		if !slices.Contains(group, name) {
+			continue
+		}
+		for _, other := range group {
+			if flags.Changed(other) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// display returns the value to include in messages, which is redacted for secrets
+func (s setting) display(value string) string {
+	if s.secret {
+		return redacted
+	}
+	return strconv.Quote(value)
+}
+
+// profileValue converts a profile field into flag values, reporting whether the field is set
+func profileValue(field any) ([]string, bool) {
+	switch v := field.(type) {
+	case *string:
+		return []string{*v}, *v != ""
+	case *int:
+		return []string{strconv.Itoa(*v)}, *v != 0
+	case **bool:
+		if *v == nil {
+			return nil, false
+		}
+		return []string{strconv.FormatBool(**v)}, true
+	case *[]string:
+		return *v, *v != nil
+	}
+	return nil, false
+}
+
+// setFlag sets a flag from profile values. Slices are replaced as a whole, so that their items are not parsed as CSV.
+func setFlag(flags *pflag.FlagSet, name string, values []string) error {
+	flag := flags.Lookup(name)
+	if flag == nil {
+		return fmt.Errorf("unknown flag %s", name)
+	}
+
+	if slice, ok := flag.Value.(pflag.SliceValue); ok {
+		if err := slice.Replace(values); err != nil {
+			return err
+		}
+		flag.Changed = true
+		return nil
+	}
+
+	return flags.Set(name, values[0])
+}
+
+// effectiveProfile returns the configuration held by the flags, with the secrets redacted
+func effectiveProfile(flags *pflag.FlagSet) (config.Profile, error) {
+	effective := config.Profile{}
+
+	for _, s := range settings {
+		flag := flags.Lookup(s.flag)
+
+		switch field := s.field(&effective).(type) {
+		case *string:
+			*field = flag.Value.String()
+		case *int:
+			value, err := strconv.Atoi(flag.Value.String())
+			if err != nil {
+				return effective, fmt.Errorf("invalid %s value: %w", s.flag, err)
+			}
+			*field = value
+		case **bool:
+			value, err := strconv.ParseBool(flag.Value.String())
+			if err != nil {
+				return effective, fmt.Errorf("invalid %s value: %w", s.flag, err)
+			}
+			*field = &value
+		case *[]string:
+			if slice, ok := flag.Value.(pflag.SliceValue); ok {
+				*field = slice.GetSlice()
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if field, ok := s.field(&effective).(*string); ok && s.secret && *field != "" {
+			*field = redacted
+		}
+	}
+
+	return effective, nil
+}
+
+// configShowRunFn prints the effective configuration of the download command, merged from
+// the flags, the environment, the selected profile and the defaults
+func configShowRunFn(cmd *cobra.Command, args []string) error {
+	downloadCmd := NewDownloadCmd()
+	for _, name := range []string{FlagConfig, FlagProfile} {
+		if value, _ := cmd.Flags().GetString(name); value != "" {
+			_ = downloadCmd.Flags().Set(name, value)
+		}
+	}
+
+	cfg, profile, profileName, err := loadProfile(downloadCmd)
+	if err != nil {
+		return err
+	}
+
+	if err := applySettings(downloadCmd, profile); err != nil {
+		return err
+	}
+
+	effective, err := effectiveProfile(downloadCmd.Flags())
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(effective)
+	if err != nil {
+		return fmt.Errorf("failed to encode the configuration: %w", err)
+	}
+
+	source := cfg.Path
+	if source == "" {
+		source = "none"
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "# Config file: %s\n", source)
+	fmt.Fprintf(out, "# Profile: %s\n", profileName)
+	_, err = out.Write(data)
+	return err
+}
+
+// NewConfigCmd creates the command to inspect the configuration
+func NewConfigCmd() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+	}
+
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "Print the effective configuration of the download command, with secrets redacted",
+		Args:  cobra.NoArgs,
+		RunE:  configShowRunFn,
+	}
+	addConfigFlags(showCmd)
+
+	configCmd.AddCommand(showCmd)
+
+	return configCmd
+}
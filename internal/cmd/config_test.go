@@ -0,0 +1,123 @@
+package cmd_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/raindrop-images-dl/internal/cmd"
+)
+
+const testConfig = `
+default_profile: memes
+profiles:
+  memes:
+    api_key: profile-key
+    collection: 123
+    output: /backups/memes
+    concurrency: 8
+    gen_info_json: false
+    tags: [reaction, "cats, dogs"]
+  everything:
+    all: true
+    output: /backups/all
+`
+
+// writeTestConfig writes the test config to a temporary file, returning its path
+func writeTestConfig(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testConfig), 0o600))
+	return path
+}
+
+func TestDownloadPreFn_Config(t *testing.T) {
+	t.Run("sets flags from the default profile", func(t *testing.T) {
+		resetEnv(t)
+		downloadCmd := setupTestDownloadCmd()
+		require.NoError(t, downloadCmd.Flags().Set("config", writeTestConfig(t)))
+
+		require.NoError(t, downloadCmd.PreRunE(downloadCmd, []string{}))
+
+		collection, _ := downloadCmd.Flags().GetInt("collection")
+		concurrency, _ := downloadCmd.Flags().GetInt("concurrency")
+		genInfo, _ := downloadCmd.Flags().GetBool("gen-info-json")
+		tags, _ := downloadCmd.Flags().GetStringSlice("tag")
+
+		assert.Equal(t, 123, collection)
+		assert.Equal(t, 8, concurrency)
+		assert.False(t, genInfo)
+		assert.Equal(t, []string{"reaction", "cats, dogs"}, tags)
+	})
+
+	t.Run("prefers flags over environment variables over the profile", func(t *testing.T) {
+		resetEnv(t)
+		t.Setenv("RAINDROP_CONFIG", writeTestConfig(t))
+		t.Setenv("OUTPUT_DIR", "/from/env")
+		t.Setenv("RAINDROP_API_KEY", "env-key")
+
+		downloadCmd := setupTestDownloadCmd()
+		require.NoError(t, downloadCmd.Flags().Set("api-key", "flag-key"))
+
+		require.NoError(t, downloadCmd.PreRunE(downloadCmd, []string{}))
+
+		apiKey, _ := downloadCmd.Flags().GetString("api-key")
+		output, _ := downloadCmd.Flags().GetString("output")
+
+		assert.Equal(t, "flag-key", apiKey)
+		assert.Equal(t, "/from/env", output)
+	})
+
+	t.Run("selects the profile with the profile flag", func(t *testing.T) {
+		resetEnv(t)
+		t.Setenv("RAINDROP_COLLECTION", "456")
+
+		downloadCmd := setupTestDownloadCmd()
+		require.NoError(t, downloadCmd.Flags().Set("config", writeTestConfig(t)))
+		require.NoError(t, downloadCmd.Flags().Set("profile", "everything"))
+
+		require.NoError(t, downloadCmd.PreRunE(downloadCmd, []string{}))
+
+		// The collection from the environment takes precedence over --all from the profile
+		collection, _ := downloadCmd.Flags().GetInt("collection")
+		assert.Equal(t, 456, collection)
+		assert.False(t, downloadCmd.Flags().Changed("all"))
+	})
+
+	t.Run("returns error when the profile does not exist", func(t *testing.T) {
+		resetEnv(t)
+		downloadCmd := setupTestDownloadCmd()
+		require.NoError(t, downloadCmd.Flags().Set("config", writeTestConfig(t)))
+		require.NoError(t, downloadCmd.Flags().Set("profile", "work"))
+
+		err := downloadCmd.PreRunE(downloadCmd, []string{})
+		assert.ErrorContains(t, err, "profile not found")
+	})
+}
+
+func TestConfigShow(t *testing.T) {
+	resetEnv(t)
+	t.Setenv("OUTPUT_DIR", "/from/env")
+
+	configPath := writeTestConfig(t)
+
+	configCmd := cmd.NewConfigCmd()
+	out := &bytes.Buffer{}
+	configCmd.SetOut(out)
+	configCmd.SetArgs([]string{"show", "--config", configPath})
+
+	require.NoError(t, configCmd.Execute())
+
+	assert.Contains(t, out.String(), "# Config file: "+configPath)
+	assert.Contains(t, out.String(), "# Profile: memes")
+	assert.Contains(t, out.String(), "api_key: <redacted>")
+	assert.NotContains(t, out.String(), "profile-key")
+	assert.Contains(t, out.String(), "output: /from/env")
+	assert.Contains(t, out.String(), "collection: 123")
+	assert.Contains(t, out.String(), "types:\n    - image")
+}
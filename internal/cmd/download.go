@@ -1,86 +1,321 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os"
+	"io"
+	"log/slog"
+	"slices"
 
 	"github.com/spf13/cobra"
 
 	"github.com/brpaz/raindrop-images-dl/internal/downloader"
+	"github.com/brpaz/raindrop-images-dl/internal/metrics"
 	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
 )
 
 const (
-	FlagDownloadCollection = "collection"
-	FlagDownloadOutput     = "output"
-	FlagDownloadGenInfo    = "gen-info-json"
-	FlagDownloadApiKey     = "api-key"
+	FlagDownloadCollection  = "collection"
+	FlagDownloadOutput      = "output"
+	FlagDownloadGenInfo     = "gen-info-json"
+	FlagDownloadApiKey      = "api-key"
+	FlagDownloadConcurrency = "concurrency"
+	FlagDownloadMirror      = "mirror"
+	FlagDownloadPrune       = "prune"
+	FlagDownloadDryRun      = "dry-run"
+	FlagDownloadAllMedia    = "all-media"
+	FlagDownloadSource      = "source"
+	FlagDownloadNameTmpl    = "name-template"
+	FlagDownloadRecursive   = "recursive"
+	FlagDownloadAll         = "all"
+	FlagDownloadInclude     = "include"
+	FlagDownloadExclude     = "exclude"
+	FlagDownloadQuery       = "query"
+	FlagDownloadTag         = "tag"
+	FlagDownloadSince       = "since"
+	FlagDownloadUntil       = "until"
+	FlagDownloadDomain      = "domain"
+	FlagDownloadTypes       = "types"
+	FlagDownloadReport      = "report"
+	FlagDownloadProgress    = "progress"
 )
 
+// downloadPreFn sets the flags that were not passed from the environment and the config profile,
+// and reads the API key from its file or command
 func downloadPreFn(cmd *cobra.Command, args []string) error {
-	// Set the flags from environment variables if not provided
-	collection, _ := cmd.Flags().GetInt(FlagDownloadCollection)
-	if collection == 0 {
-		envCollection := os.Getenv("RAINDROP_COLLECTION")
-		if envCollection != "" {
-			_ = cmd.Flags().Set(FlagDownloadCollection, envCollection)
-		}
+	_, profile, profileName, err := loadProfile(cmd)
+	if err != nil {
+		return err
 	}
 
-	output, _ := cmd.Flags().GetString(FlagDownloadOutput)
-	if output == "" {
-		envOutput := os.Getenv("OUTPUT_DIR")
-		if envOutput != "" {
-			_ = cmd.Flags().Set(FlagDownloadOutput, envOutput)
-		}
+	// Keep the resolved profile, whose saved credentials are used when no API key is set
+	if err := cmd.Flags().Set(FlagProfile, profileName); err != nil {
+		return err
+	}
+
+	if err := applySettings(cmd, profile); err != nil {
+		return err
 	}
 
+	return resolveAPIKey(cmd)
+}
+
+func downloadRunFn(cmd *cobra.Command, args []string) error {
+	collection, _ := cmd.Flags().GetInt(FlagDownloadCollection)
+	output, _ := cmd.Flags().GetString(FlagDownloadOutput)
 	apiKey, _ := cmd.Flags().GetString(FlagDownloadApiKey)
+	infoJson, _ := cmd.Flags().GetBool(FlagDownloadGenInfo)
+	concurrency, _ := cmd.Flags().GetInt(FlagDownloadConcurrency)
+	mirror, _ := cmd.Flags().GetBool(FlagDownloadMirror)
+	prune, _ := cmd.Flags().GetString(FlagDownloadPrune)
+	dryRun, _ := cmd.Flags().GetBool(FlagDownloadDryRun)
+	allMedia, _ := cmd.Flags().GetBool(FlagDownloadAllMedia)
+	sourceFlag, _ := cmd.Flags().GetString(FlagDownloadSource)
+	nameTmplFlag, _ := cmd.Flags().GetString(FlagDownloadNameTmpl)
+	recursive, _ := cmd.Flags().GetBool(FlagDownloadRecursive)
+	all, _ := cmd.Flags().GetBool(FlagDownloadAll)
+	include, _ := cmd.Flags().GetStringSlice(FlagDownloadInclude)
+	exclude, _ := cmd.Flags().GetStringSlice(FlagDownloadExclude)
+	typeNames, _ := cmd.Flags().GetStringSlice(FlagDownloadTypes)
+	reportPath, _ := cmd.Flags().GetString(FlagDownloadReport)
+	progressMode, _ := cmd.Flags().GetString(FlagDownloadProgress)
+	metricsAddr, _ := cmd.Flags().GetString(FlagDownloadMetricsAddr)
+
+	// --mirror is a shortcut for moving the files of deleted drops to the trash
+	if mirror && prune == "" {
+		prune = string(downloader.PruneTrash)
+	}
+
+	pruneMode, err := downloader.ParsePruneMode(prune)
+	if err != nil {
+		return err
+	}
+
+	source, err := downloader.ParseSource(sourceFlag)
+	if err != nil {
+		return err
+	}
+
+	nameTmpl, err := downloader.ParseNameTemplate(nameTmplFlag)
+	if err != nil {
+		return err
+	}
+
+	types, err := downloader.ParseTypes(typeNames)
+	if err != nil {
+		return err
+	}
+
+	search, err := searchFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	showProgress, err := progressEnabled(progressMode, cmd.OutOrStdout())
+	if err != nil {
+		return err
+	}
+
+	schedule, err := watchSchedule(cmd)
+	if err != nil {
+		return err
+	}
+
+	auth := raindrop.WithAPIKey(apiKey)
 	if apiKey == "" {
-		envApiKey := os.Getenv("RAINDROP_API_KEY")
+		profileName, _ := cmd.Flags().GetString(FlagProfile)
+		tokenSource, err := oauthTokenSource(cmd.Context(), profileName)
+		if err != nil {
+			return err
+		}
+		auth = raindrop.WithTokenSource(tokenSource)
+	}
 
-		if envApiKey != "" {
-			_ = cmd.Flags().Set(FlagDownloadApiKey, envApiKey)
+	clientOpts := []raindrop.Option{auth}
+
+	var syncMetrics *metrics.Metrics
+	if metricsAddr != "" {
+		syncMetrics = metrics.New()
+		stopMetrics, err := startMetricsServer(metricsAddr, syncMetrics)
+		if err != nil {
+			return err
 		}
+		defer stopMetrics()
+
+		clientOpts = append(clientOpts, raindrop.WithObserver(syncMetrics.Client()))
 	}
 
-	isGenEnvInfoJsonSwet := cmd.Flags().Changed(FlagDownloadGenInfo)
+	raindropClient, err := raindrop.NewClient(clientOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to initialize Raindrop.io client: %w", err)
+	}
 
-	if !isGenEnvInfoJsonSwet {
-		envGenInfoJson := os.Getenv("GEN_INFO_JSON")
+	// Check the credentials before starting, so that an invalid key is not reported as a missing collection
+	user, err := raindropClient.GetUser(cmd.Context())
+	if err != nil {
+		return downloadError(collection, fmt.Errorf("failed to validate the credentials: %w", err))
+	}
+	slog.Debug("Authenticated to Raindrop.io", "user", user.ID)
 
-		if envGenInfoJson != "" {
-			_ = cmd.Flags().Set(FlagDownloadGenInfo, envGenInfoJson)
+	opts := []downloader.Option{
+		downloader.WithRaindropClient(raindropClient),
+		downloader.WithConcurrency(concurrency),
+		downloader.WithPruneMode(pruneMode),
+		downloader.WithDryRun(dryRun),
+		downloader.WithAllMedia(allMedia),
+		downloader.WithSource(source),
+		downloader.WithNameTemplate(nameTmpl),
+		downloader.WithRecursive(recursive),
+		downloader.WithCollectionFilter(downloader.CollectionFilter{Include: include, Exclude: exclude}),
+		downloader.WithSearch(search),
+		downloader.WithTypes(types...),
+	}
+
+	// A dry run downloads nothing, so its drops are not counted
+	if syncMetrics != nil && !dryRun {
+		opts = append(opts, downloader.WithObserver(syncMetrics.Downloader()))
+	}
+
+	sync := func(ctx context.Context) error {
+		err := runDownload(ctx, cmd.OutOrStdout(), opts, syncOptions{
+			collection:   collection,
+			all:          all,
+			output:       output,
+			infoJSON:     infoJson,
+			reportPath:   reportPath,
+			showProgress: showProgress,
+		})
+
+		// An interrupted sync is neither a success nor a failure
+		if syncMetrics != nil && ctx.Err() == nil {
+			syncMetrics.RecordSync(err)
 		}
+		return err
 	}
 
-	return nil
+	if schedule != nil {
+		return watch(cmd.Context(), schedule, sync)
+	}
+
+	return sync(cmd.Context())
 }
 
-func downloadRunFn(cmd *cobra.Command, args []string) error {
-	collection, _ := cmd.Flags().GetInt(FlagDownloadCollection)
-	output, _ := cmd.Flags().GetString(FlagDownloadOutput)
-	apiKey, _ := cmd.Flags().GetString(FlagDownloadApiKey)
-	infoJson, _ := cmd.Flags().GetBool(FlagDownloadGenInfo)
+// syncOptions are the settings of a single download run
+type syncOptions struct {
+	collection   int
+	all          bool
+	output       string
+	infoJSON     bool
+	reportPath   string
+	showProgress bool
+}
 
-	raindropClient, err := raindrop.NewClient(raindrop.WithAPIKey(apiKey))
-	if err != nil {
-		return fmt.Errorf("failed to initialize Raindrop.io client: %w", err)
+// runDownload downloads the selected collections once, printing the summary of the run
+func runDownload(ctx context.Context, out io.Writer, opts []downloader.Option, run syncOptions) error {
+	var progress *progressDisplay
+	if run.showProgress {
+		progress = newProgressDisplay(out)
+		defer progress.stop()
+		opts = append(slices.Clip(opts), downloader.WithObserver(progress))
+
+		// The progress line replaces the log of each item, warnings and errors are still logged
+		defer slog.SetLogLoggerLevel(slog.SetLogLoggerLevel(slog.LevelWarn))
 	}
 
-	dl, err := downloader.NewDownloader(downloader.WithRaindropClient(raindropClient))
+	dl, err := downloader.NewDownloader(opts...)
 	if err != nil {
 		return fmt.Errorf("failed to initialize downloader: %w", err)
 	}
 
-	err = dl.DownloadCollection(cmd.Context(), collection, output, infoJson)
+	var result *downloader.Result
+	if run.all {
+		result, err = dl.DownloadAllCollections(ctx, run.output, run.infoJSON)
+	} else {
+		result, err = dl.DownloadCollection(ctx, run.collection, run.output, run.infoJSON)
+	}
+
+	if progress != nil {
+		progress.stop()
+	}
+
+	if result != nil {
+		printSummary(out, result)
+
+		if run.reportPath != "" {
+			if reportErr := writeReport(run.reportPath, result); reportErr != nil {
+				return errors.Join(reportErr, err)
+			}
+		}
+	}
+
 	if err != nil {
-		return fmt.Errorf("failed to download collection: %w", err)
+		return downloadError(run.collection, err)
 	}
 
 	return nil
 }
 
+// searchFromFlags builds the Raindrop search from the filter flags
+func searchFromFlags(cmd *cobra.Command) (raindrop.Search, error) {
+	query, _ := cmd.Flags().GetString(FlagDownloadQuery)
+	tags, _ := cmd.Flags().GetStringSlice(FlagDownloadTag)
+	domain, _ := cmd.Flags().GetString(FlagDownloadDomain)
+
+	search := raindrop.NewSearch().Query(query).Tags(tags...).Domain(domain)
+
+	if since, _ := cmd.Flags().GetString(FlagDownloadSince); since != "" {
+		date, err := raindrop.ParseSearchDate(since)
+		if err != nil {
+			return search, fmt.Errorf("invalid --%s date, expected YYYY-MM-DD: %w", FlagDownloadSince, err)
+		}
+		search = search.Since(date)
+	}
+
+	if until, _ := cmd.Flags().GetString(FlagDownloadUntil); until != "" {
+		date, err := raindrop.ParseSearchDate(until)
+		if err != nil {
+			return search, fmt.Errorf("invalid --%s date, expected YYYY-MM-DD: %w", FlagDownloadUntil, err)
+		}
+		search = search.Until(date)
+	}
+
+	return search, nil
+}
+
+// downloadError maps errors from the Raindrop API into actionable messages and exit codes.
+func downloadError(collection int, err error) error {
+	switch {
+	case errors.Is(err, raindrop.ErrUnauthorized):
+		return &ExitError{
+			Code: ExitCodeUnauthorized,
+			Err:  fmt.Errorf("the Raindrop.io credentials were rejected, check the --%s flag or the RAINDROP_API_KEY environment variable, or run the login command again: %w", FlagDownloadApiKey, err),
+		}
+	case errors.Is(err, raindrop.ErrNotFound) && collection == 0:
+		return &ExitError{
+			Code: ExitCodeNotFound,
+			Err:  fmt.Errorf("a collection was not found, it may have been deleted during the download: %w", err),
+		}
+	case errors.Is(err, raindrop.ErrNotFound):
+		return &ExitError{
+			Code: ExitCodeNotFound,
+			Err:  fmt.Errorf("collection %d was not found, check the collection ID in the Raindrop.io web app URL: %w", collection, err),
+		}
+	case errors.Is(err, raindrop.ErrRateLimited):
+		return &ExitError{
+			Code: ExitCodeRateLimited,
+			Err:  fmt.Errorf("the Raindrop.io rate limit was exceeded, try again later: %w", err),
+		}
+	case errors.Is(err, downloader.ErrPartialFailure):
+		return &ExitError{
+			Code: ExitCodePartialFailure,
+			Err:  err,
+		}
+	}
+
+	return fmt.Errorf("failed to download collection: %w", err)
+}
+
 func NewDownloadCmd() *cobra.Command {
 	downloadCmd := &cobra.Command{
 		Use:     "download",
@@ -91,12 +326,42 @@ func NewDownloadCmd() *cobra.Command {
 
 	downloadCmd.Flags().IntP(FlagDownloadCollection, "c", 0, "The collection ID to download images from")
 	downloadCmd.Flags().StringP(FlagDownloadOutput, "o", "", "The output directory to save the images")
-	downloadCmd.Flags().StringP(FlagDownloadApiKey, "k", "", "The Raindrop.io API key")
+	downloadCmd.Flags().StringP(FlagDownloadApiKey, "k", "", "The Raindrop.io API key. Prefer --api-key-file or --api-key-command, to keep it out of the shell history")
+	downloadCmd.Flags().String(FlagDownloadApiKeyFile, "", "Read the Raindrop.io API key from this file, or from stdin with \"-\" (env: RAINDROP_API_KEY_FILE)")
+	downloadCmd.Flags().String(FlagDownloadApiKeyCommand, "", "Read the Raindrop.io API key from the output of this command, ex: \"pass show raindrop\" (env: RAINDROP_API_KEY_COMMAND)")
 	downloadCmd.Flags().BoolP(FlagDownloadGenInfo, "i", true, "Generate a JSON file with the image metadata")
+	downloadCmd.Flags().IntP(FlagDownloadConcurrency, "j", downloader.DefaultConcurrency, "The number of images to download in parallel")
+	downloadCmd.Flags().Bool(FlagDownloadMirror, false, "Mirror the collection, moving the images of deleted drops to the .trash folder")
+	downloadCmd.Flags().String(FlagDownloadPrune, "", "What to do with the images of deleted drops: \"trash\" or \"delete\"")
+	downloadCmd.Flags().Bool(FlagDownloadDryRun, false, "Show the files that would be created, skipped, overwritten or pruned, without downloading or changing them")
+	downloadCmd.Flags().Bool(FlagDownloadAllMedia, false, "Download all the media of a drop, not just its cover")
+	downloadCmd.Flags().String(FlagDownloadNameTmpl, downloader.DefaultNameTemplate, "The Go template used to name the saved files, executed over the Raindrop drop")
+	downloadCmd.Flags().BoolP(FlagDownloadRecursive, "r", false, "Also download the nested collections, each into a sub-directory of its parent")
+	downloadCmd.Flags().Bool(FlagDownloadAll, false, "Download all the collections of the account, including the Unsorted one")
+	downloadCmd.Flags().StringSlice(FlagDownloadInclude, nil, "Only download the collections with these names or IDs, and their nested collections")
+	downloadCmd.Flags().StringSlice(FlagDownloadExclude, nil, "Skip the collections with these names or IDs, and their nested collections")
+	downloadCmd.Flags().String(FlagDownloadQuery, "", "Only download the drops matching this Raindrop search query")
+	downloadCmd.Flags().StringSlice(FlagDownloadTag, nil, "Only download the drops with these tags")
+	downloadCmd.Flags().String(FlagDownloadSince, "", "Only download the drops created after this date (YYYY-MM-DD)")
+	downloadCmd.Flags().String(FlagDownloadUntil, "", "Only download the drops created before this date (YYYY-MM-DD)")
+	downloadCmd.Flags().String(FlagDownloadDomain, "", "Only download the drops whose link is on this domain")
+	downloadCmd.Flags().StringSlice(FlagDownloadTypes, []string{string(raindrop.DropTypeImage)}, "The types of drops to download: \"image\", \"video\", \"document\", \"audio\" or \"article\"")
+	downloadCmd.Flags().String(FlagDownloadProgress, ProgressAuto, "When to display a progress line: \"auto\" (when the output is a terminal), \"always\" or \"never\"")
+	downloadCmd.Flags().String(FlagDownloadReport, "", "Write a JSON report of the run to this file")
+	downloadCmd.Flags().String(FlagDownloadSource, string(downloader.SourceCover), "Where to download images from: \"cover\", \"cache\" (permanent copy), \"link\" or \"auto\"")
+
+	downloadCmd.Flags().Bool(FlagDownloadWatch, false, "Keep running, and sync the collections again on a schedule (env: RAINDROP_WATCH)")
+	downloadCmd.Flags().Duration(FlagDownloadInterval, DefaultWatchInterval, "The time between two syncs in watch mode, ex: \"30m\" (env: RAINDROP_INTERVAL)")
+	downloadCmd.Flags().String(FlagDownloadSchedule, "", "The cron expression of the syncs in watch mode, ex: \"0 */6 * * *\", instead of --interval (env: RAINDROP_SCHEDULE)")
+
+	downloadCmd.Flags().String(FlagDownloadMetricsAddr, "", "Serve the Prometheus /metrics and the /healthz and /readyz endpoints on this address, ex: \":9090\" (env: RAINDROP_METRICS_ADDR)")
+
+	addConfigFlags(downloadCmd)
 
-	_ = downloadCmd.MarkFlagRequired(FlagDownloadCollection)
-	_ = downloadCmd.MarkFlagRequired(FlagDownloadApiKey)
 	_ = downloadCmd.MarkFlagRequired(FlagDownloadOutput)
+	downloadCmd.MarkFlagsOneRequired(FlagDownloadCollection, FlagDownloadAll)
+	downloadCmd.MarkFlagsMutuallyExclusive(FlagDownloadCollection, FlagDownloadAll)
+	downloadCmd.MarkFlagsMutuallyExclusive(FlagDownloadInterval, FlagDownloadSchedule)
 
 	return downloadCmd
 }
@@ -20,6 +20,19 @@ func resetEnv(t *testing.T) {
 	t.Setenv("RAINDROP_COLLECTION", "")
 	t.Setenv("OUTPUT_DIR", "")
 	t.Setenv("RAINDROP_API_KEY", "")
+	t.Setenv("RAINDROP_API_KEY_FILE", "")
+	t.Setenv("RAINDROP_API_KEY_COMMAND", "")
+	t.Setenv("GEN_INFO_JSON", "")
+	t.Setenv("RAINDROP_CONFIG", "")
+	t.Setenv("RAINDROP_PROFILE", "")
+	t.Setenv("RAINDROP_CLIENT_ID", "")
+	t.Setenv("RAINDROP_CLIENT_SECRET", "")
+	t.Setenv("RAINDROP_WATCH", "")
+	t.Setenv("RAINDROP_INTERVAL", "")
+	t.Setenv("RAINDROP_SCHEDULE", "")
+	t.Setenv("RAINDROP_METRICS_ADDR", "")
+	// Isolate the tests from the config file of the user
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
 }
 
 func TestNewDownloadCmd(t *testing.T) {
@@ -29,10 +42,25 @@ func TestNewDownloadCmd(t *testing.T) {
 
 	assert.IsType(t, &cobra.Command{}, downloadCmd)
 	assert.Equal(t, "download", downloadCmd.Use)
+	assert.NotNil(t, downloadCmd.Flags().Lookup("report"))
 }
 
 func TestDownloadPreFn(t *testing.T) {
+	t.Run("ignores the collection environment variable with all collections", func(t *testing.T) {
+		resetEnv(t)
+		t.Setenv("RAINDROP_COLLECTION", "123")
+
+		downloadCmd := setupTestDownloadCmd()
+		require.NoError(t, downloadCmd.Flags().Set("all", "true"))
+
+		err := downloadCmd.PreRunE(downloadCmd, []string{})
+		require.NoError(t, err)
+
+		assert.False(t, downloadCmd.Flags().Changed("collection"))
+	})
+
 	t.Run("sets flags from environment variables", func(t *testing.T) {
+		resetEnv(t)
 		t.Setenv("RAINDROP_COLLECTION", "123")
 		t.Setenv("OUTPUT_DIR", "/some/path")
 		t.Setenv("RAINDROP_API_KEY", "test-key")
@@ -63,6 +91,113 @@ func TestDownloadExecute(t *testing.T) {
 		err := downloadCmd.ExecuteContext(context.Background())
 		require.Error(t, err)
 
-		assert.Contains(t, err.Error(), "required flag(s) \"api-key\", \"collection\", \"output\" not set")
+		assert.Contains(t, err.Error(), "required flag(s) \"output\" not set")
+	})
+
+	t.Run("returns error when a search date is invalid", func(t *testing.T) {
+		resetEnv(t)
+		downloadCmd := setupTestDownloadCmd()
+		downloadCmd.SetArgs([]string{"-k", "test-key", "-o", t.TempDir(), "-c", "123", "--since", "last week"})
+
+		err := downloadCmd.ExecuteContext(context.Background())
+		require.Error(t, err)
+
+		assert.Contains(t, err.Error(), "invalid --since date")
+	})
+
+	t.Run("returns error when the progress mode is invalid", func(t *testing.T) {
+		resetEnv(t)
+		downloadCmd := setupTestDownloadCmd()
+		downloadCmd.SetArgs([]string{"-k", "test-key", "-o", t.TempDir(), "-c", "123", "--progress", "sometimes"})
+
+		err := downloadCmd.ExecuteContext(context.Background())
+		require.Error(t, err)
+
+		assert.Contains(t, err.Error(), "invalid --progress mode")
+	})
+
+	t.Run("returns error when neither a collection nor all collections are selected", func(t *testing.T) {
+		resetEnv(t)
+		downloadCmd := setupTestDownloadCmd()
+		downloadCmd.SetArgs([]string{"-k", "test-key", "-o", t.TempDir()})
+
+		err := downloadCmd.ExecuteContext(context.Background())
+		require.Error(t, err)
+
+		assert.Contains(t, err.Error(), "at least one of the flags in the group [collection all] is required")
+	})
+
+	t.Run("returns error when both a collection and all collections are selected", func(t *testing.T) {
+		resetEnv(t)
+		downloadCmd := setupTestDownloadCmd()
+		downloadCmd.SetArgs([]string{"-k", "test-key", "-o", t.TempDir(), "-c", "123", "--all"})
+
+		err := downloadCmd.ExecuteContext(context.Background())
+		require.Error(t, err)
+
+		assert.Contains(t, err.Error(), "if any flags in the group [collection all] are set none of the others can be")
+	})
+}
+
+func TestDownloadExecute_Watch(t *testing.T) {
+	t.Run("returns error when the cron expression is invalid", func(t *testing.T) {
+		resetEnv(t)
+		downloadCmd := setupTestDownloadCmd()
+		downloadCmd.SetArgs([]string{"-k", "test-key", "-o", t.TempDir(), "-c", "123", "--watch", "--schedule", "every hour"})
+
+		err := downloadCmd.ExecuteContext(context.Background())
+		require.Error(t, err)
+
+		assert.Contains(t, err.Error(), "invalid --schedule cron expression")
+	})
+
+	t.Run("returns error when the interval is too short", func(t *testing.T) {
+		resetEnv(t)
+		downloadCmd := setupTestDownloadCmd()
+		downloadCmd.SetArgs([]string{"-k", "test-key", "-o", t.TempDir(), "-c", "123", "--watch", "--interval", "10ms"})
+
+		err := downloadCmd.ExecuteContext(context.Background())
+		require.Error(t, err)
+
+		assert.Contains(t, err.Error(), "invalid --interval 10ms")
+	})
+
+	t.Run("returns error when both an interval and a schedule are set", func(t *testing.T) {
+		resetEnv(t)
+		downloadCmd := setupTestDownloadCmd()
+		downloadCmd.SetArgs([]string{"-k", "test-key", "-o", t.TempDir(), "-c", "123", "--watch", "--interval", "30m", "--schedule", "0 * * * *"})
+
+		err := downloadCmd.ExecuteContext(context.Background())
+		require.Error(t, err)
+
+		assert.Contains(t, err.Error(), "if any flags in the group [interval schedule] are set none of the others can be")
+	})
+
+	t.Run("ignores the interval of the environment with a schedule", func(t *testing.T) {
+		resetEnv(t)
+		t.Setenv("RAINDROP_WATCH", "true")
+		t.Setenv("RAINDROP_INTERVAL", "30m")
+
+		downloadCmd := setupTestDownloadCmd()
+		require.NoError(t, downloadCmd.Flags().Set("schedule", "0 * * * *"))
+
+		require.NoError(t, downloadCmd.PreRunE(downloadCmd, []string{}))
+
+		watch, _ := downloadCmd.Flags().GetBool("watch")
+		assert.True(t, watch)
+		assert.False(t, downloadCmd.Flags().Changed("interval"))
+	})
+}
+
+func TestDownloadExecute_Metrics(t *testing.T) {
+	t.Run("returns error when the metrics address can not be used", func(t *testing.T) {
+		resetEnv(t)
+		downloadCmd := setupTestDownloadCmd()
+		downloadCmd.SetArgs([]string{"-k", "test-key", "-o", t.TempDir(), "-c", "123", "--metrics-addr", "localhost:-1"})
+
+		err := downloadCmd.ExecuteContext(context.Background())
+		require.Error(t, err)
+
+		assert.Contains(t, err.Error(), "failed to listen on the --metrics-addr")
 	})
 }
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"errors"
+)
+
+// Exit codes returned by the CLI, so that scripts can react to the different failure causes.
+const (
+	ExitCodeError        = 1
+	ExitCodeUnauthorized = 3
+	ExitCodeNotFound     = 4
+	ExitCodeRateLimited  = 5
+	// ExitCodePartialFailure is returned when the run completed, but some drops or pages could not be downloaded
+	ExitCodePartialFailure = 6
+)
+
+// ExitError is an error that carries the exit code the process should terminate with.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code for an error returned by a command.
+func ExitCode(err error) int {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return ExitCodeError
+}
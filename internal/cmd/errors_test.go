@@ -0,0 +1,30 @@
+package cmd_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/brpaz/raindrop-images-dl/internal/cmd"
+)
+
+func TestExitCode(t *testing.T) {
+	t.Parallel()
+
+	t.Run("WithExitError_ReturnsItsCode", func(t *testing.T) {
+		t.Parallel()
+
+		err := fmt.Errorf("wrapped: %w", &cmd.ExitError{Code: cmd.ExitCodeNotFound, Err: errors.New("not found")})
+
+		assert.Equal(t, cmd.ExitCodeNotFound, cmd.ExitCode(err))
+		assert.Equal(t, "wrapped: not found", err.Error())
+	})
+
+	t.Run("WithGenericError_ReturnsDefaultCode", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, cmd.ExitCodeError, cmd.ExitCode(errors.New("boom")))
+	})
+}
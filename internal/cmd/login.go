@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/oauth2"
+
+	"github.com/brpaz/raindrop-images-dl/internal/config"
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+const (
+	FlagLoginClientID     = "client-id"
+	FlagLoginClientSecret = "client-secret"
+	FlagLoginRedirectURL  = "redirect-url"
+	FlagLoginTimeout      = "timeout"
+)
+
+// DefaultRedirectURL is the loopback address that receives the authorization code.
+// It must be registered as the redirect URI of the Raindrop app.
+const DefaultRedirectURL = "http://localhost:8765/callback"
+
+var errNoCredentials = errors.New("no Raindrop.io credentials")
+
+// loginRunFn authorizes the app on behalf of the user, and saves the token in the credentials of the profile
+func loginRunFn(cmd *cobra.Command, args []string) error {
+	clientID := flagOrEnv(cmd, FlagLoginClientID, "RAINDROP_CLIENT_ID")
+	clientSecret := flagOrEnv(cmd, FlagLoginClientSecret, "RAINDROP_CLIENT_SECRET")
+	redirectURL, _ := cmd.Flags().GetString(FlagLoginRedirectURL)
+	timeout, _ := cmd.Flags().GetDuration(FlagLoginTimeout)
+
+	if clientID == "" || clientSecret == "" {
+		return fmt.Errorf("the --%s and --%s of the Raindrop.io app are required, create an app in the Raindrop.io integration settings", FlagLoginClientID, FlagLoginClientSecret)
+	}
+
+	_, _, profileName, err := loadProfile(cmd)
+	if err != nil {
+		return err
+	}
+
+	store, err := config.DefaultCredentialStore()
+	if err != nil {
+		return fmt.Errorf("failed to locate the credentials directory: %w", err)
+	}
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	oauthConfig := raindrop.NewOAuth2Config(clientID, clientSecret, redirectURL)
+	out := cmd.OutOrStdout()
+
+	token, err := raindrop.Authorize(ctx, oauthConfig, func(authURL string) error {
+		_, err := fmt.Fprintf(out, "Open this URL in your browser to authorize raindrop-images-dl:\n\n  %s\n\n", authURL)
+		return err
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("the authorization was not completed within %s", timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to authorize: %w", err)
+	}
+
+	creds := &config.Credentials{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Token:        token,
+	}
+
+	client, err := raindrop.NewClient(raindrop.WithTokenSource(oauthConfig.TokenSource(ctx, token)))
+	if err != nil {
+		return fmt.Errorf("failed to initialize Raindrop.io client: %w", err)
+	}
+
+	user, err := client.GetUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to validate the token: %w", err)
+	}
+
+	if err := store.Save(profileName, creds); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Logged in as %s, the credentials of profile %q are saved in %s\n", user.FullName, profileName, store.Dir)
+	return nil
+}
+
+// oauthTokenSource returns the token source of the credentials saved by the login command for a profile.
+// Refreshed tokens are saved back to the credentials.
+func oauthTokenSource(ctx context.Context, profileName string) (oauth2.TokenSource, error) {
+	store, err := config.DefaultCredentialStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate the credentials directory: %w", err)
+	}
+
+	creds, err := store.Load(profileName)
+	if errors.Is(err, config.ErrCredentialsNotFound) {
+		return nil, fmt.Errorf("%w: set --%s, --%s or --%s, or run \"raindrop-images-dl login --profile %s\"",
+			errNoCredentials, FlagDownloadApiKey, FlagDownloadApiKeyFile, FlagDownloadApiKeyCommand, profileName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if creds.Token == nil {
+		return nil, fmt.Errorf("%w: the credentials of profile %q have no token, run the login command again", errNoCredentials, profileName)
+	}
+
+	oauthConfig := raindrop.NewOAuth2Config(creds.ClientID, creds.ClientSecret, creds.RedirectURL)
+	return store.TokenSource(profileName, creds, oauthConfig.TokenSource(ctx, creds.Token)), nil
+}
+
+// NewLoginCmd creates the command that authorizes a Raindrop.io app with OAuth2, as an alternative to API keys
+func NewLoginCmd() *cobra.Command {
+	loginCmd := &cobra.Command{
+		Use:   "login",
+		Short: "Authorize a Raindrop.io account with OAuth2, and save its credentials for the profile",
+		Long: `Authorize a Raindrop.io account with OAuth2, and save its credentials for the profile.
+
+The client ID and secret are those of an app created in the Raindrop.io integration settings,
+whose redirect URI must match --redirect-url. Once logged in, the download command uses the
+saved credentials of the profile when no API key is set, refreshing the token when it expires.`,
+		Args: cobra.NoArgs,
+		RunE: loginRunFn,
+	}
+
+	loginCmd.Flags().String(FlagLoginClientID, "", "The client ID of the Raindrop.io app (env: RAINDROP_CLIENT_ID)")
+	loginCmd.Flags().String(FlagLoginClientSecret, "", "The client secret of the Raindrop.io app (env: RAINDROP_CLIENT_SECRET)")
+	loginCmd.Flags().String(FlagLoginRedirectURL, DefaultRedirectURL, "The local redirect URI registered for the app, where the authorization code is received")
+	loginCmd.Flags().Duration(FlagLoginTimeout, 5*time.Minute, "How long to wait for the authorization")
+
+	addConfigFlags(loginCmd)
+
+	return loginCmd
+}
@@ -0,0 +1,63 @@
+package cmd_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/raindrop-images-dl/internal/cmd"
+)
+
+func TestNewLoginCmd(t *testing.T) {
+	t.Parallel()
+
+	loginCmd := cmd.NewLoginCmd()
+
+	assert.IsType(t, &cobra.Command{}, loginCmd)
+	assert.Equal(t, "login", loginCmd.Use)
+	assert.Equal(t, cmd.DefaultRedirectURL, loginCmd.Flags().Lookup("redirect-url").DefValue)
+}
+
+func TestLoginExecute(t *testing.T) {
+	t.Run("returns error when the app credentials are not set", func(t *testing.T) {
+		resetEnv(t)
+		loginCmd := cmd.NewLoginCmd()
+		loginCmd.SetArgs([]string{"--client-id", "client-id"})
+
+		err := loginCmd.ExecuteContext(context.Background())
+		require.Error(t, err)
+
+		assert.Contains(t, err.Error(), "the --client-id and --client-secret of the Raindrop.io app are required")
+	})
+
+	t.Run("returns error when the profile does not exist", func(t *testing.T) {
+		resetEnv(t)
+		t.Setenv("RAINDROP_CLIENT_ID", "client-id")
+		t.Setenv("RAINDROP_CLIENT_SECRET", "client-secret")
+
+		loginCmd := cmd.NewLoginCmd()
+		loginCmd.SetArgs([]string{"--config", writeTestConfig(t), "--profile", "unknown"})
+
+		err := loginCmd.ExecuteContext(context.Background())
+		require.Error(t, err)
+
+		assert.Contains(t, err.Error(), "profile not found")
+	})
+}
+
+func TestDownloadExecute_Credentials(t *testing.T) {
+	t.Run("suggests to login when no API key or credentials are set", func(t *testing.T) {
+		resetEnv(t)
+		downloadCmd := setupTestDownloadCmd()
+		downloadCmd.SetArgs([]string{"-o", t.TempDir(), "-c", "123"})
+
+		err := downloadCmd.ExecuteContext(context.Background())
+		require.Error(t, err)
+
+		assert.Contains(t, err.Error(), "no Raindrop.io credentials")
+		assert.Contains(t, err.Error(), `run "raindrop-images-dl login --profile default"`)
+	})
+}
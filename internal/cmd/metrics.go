@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/brpaz/raindrop-images-dl/internal/metrics"
+)
+
+const FlagDownloadMetricsAddr = "metrics-addr"
+
+// metricsShutdownTimeout is how long the metrics server waits for the pending scrapes when the command exits
+const metricsShutdownTimeout = 5 * time.Second
+
+// startMetricsServer serves the metrics and health endpoints in the background, returning the function that stops the server.
+// The address is checked right away, so that a port already in use is reported before the download starts.
+func startMetricsServer(addr string, m *metrics.Metrics) (func(), error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on the --%s: %w", FlagDownloadMetricsAddr, err)
+	}
+
+	server := &http.Server{
+		Handler:           m.NewServeMux(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("The metrics server failed", "error", err)
+		}
+	}()
+	slog.Info("Serving metrics", "address", listener.Addr().String())
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), metricsShutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}, nil
+}
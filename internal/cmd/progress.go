@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brpaz/raindrop-images-dl/internal/downloader"
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+// Progress modes of the --progress flag
+const (
+	ProgressAuto   = "auto"
+	ProgressAlways = "always"
+	ProgressNever  = "never"
+)
+
+// progressInterval is how often the progress line is redrawn
+const progressInterval = 200 * time.Millisecond
+
+// progressBarWidth is the number of characters of the progress bar
+const progressBarWidth = 24
+
+// progressEnabled reports whether the progress line should be displayed on w for the given mode
+func progressEnabled(mode string, w io.Writer) (bool, error) {
+	switch mode {
+	case ProgressAlways:
+		return true, nil
+	case ProgressNever:
+		return false, nil
+	case ProgressAuto:
+		return isTerminal(w), nil
+	}
+	return false, fmt.Errorf("invalid --%s mode %q, expected %q, %q or %q", FlagDownloadProgress, mode, ProgressAuto, ProgressAlways, ProgressNever)
+}
+
+// isTerminal reports whether w is a terminal, so that the progress line is not written to pipes and files
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// progressDisplay renders a status line with the progress of a download run, observing the downloader events.
+// The line is redrawn periodically rather than on each event, so that fast runs do not flood the terminal.
+type progressDisplay struct {
+	downloader.NopObserver
+
+	w     io.Writer
+	start time.Time
+
+	mu     sync.Mutex
+	total  int
+	done   int
+	failed int
+	bytes  int64
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	stopped  chan struct{}
+}
+
+// newProgressDisplay starts rendering the progress line on w, until stop is called
+func newProgressDisplay(w io.Writer) *progressDisplay {
+	p := &progressDisplay{
+		w:       w,
+		start:   time.Now(),
+		stopCh:  make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	go p.loop()
+
+	return p
+}
+
+// OnPageFetched adds the drops of a listing to the total, when its first page is fetched
+func (p *progressDisplay) OnPageFetched(_ raindrop.CollectionItem, _ raindrop.DropType, page int, count int) {
+	if page == 0 {
+		p.update(func() { p.total += count })
+	}
+}
+
+func (p *progressDisplay) OnItemSkipped(raindrop.Drop, string) {
+	p.update(func() { p.done++ })
+}
+
+func (p *progressDisplay) OnItemDownloaded(_ string, _ raindrop.Drop, bytes int64) {
+	p.update(func() {
+		p.done++
+		p.bytes += bytes
+	})
+}
+
+func (p *progressDisplay) OnItemFailed(raindrop.Drop, error) {
+	p.update(func() {
+		p.done++
+		p.failed++
+	})
+}
+
+// update changes the counters, which are read concurrently by the rendering loop
+func (p *progressDisplay) update(fn func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fn()
+}
+
+// stop renders the final progress and ends the line
+func (p *progressDisplay) stop() {
+	p.stopOnce.Do(func() {
+		close(p.stopCh)
+		<-p.stopped
+	})
+}
+
+func (p *progressDisplay) loop() {
+	defer close(p.stopped)
+
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.render(time.Now())
+		case <-p.stopCh:
+			p.render(time.Now())
+			fmt.Fprintln(p.w)
+			return
+		}
+	}
+}
+
+// render redraws the progress line, clearing what remains of the previous one
+func (p *progressDisplay) render(now time.Time) {
+	fmt.Fprintf(p.w, "\r%s\033[K", p.line(now))
+}
+
+// line formats the progress, like "[######------] 120/500 24% | 3 failed | 12.3 MiB | 1.2 MiB/s | ETA 2m10s"
+func (p *progressDisplay) line(now time.Time) string {
+	p.mu.Lock()
+	total, done, failed, bytes := p.total, p.done, p.failed, p.bytes
+	p.mu.Unlock()
+
+	// Drops may be added while paging, so the total is never lower than what was done
+	total = max(total, done)
+	elapsed := now.Sub(p.start)
+
+	var b strings.Builder
+
+	ratio := 0.0
+	if total > 0 {
+		ratio = float64(done) / float64(total)
+	}
+	filled := int(ratio * progressBarWidth)
+	fmt.Fprintf(&b, "[%s%s] %d/%d %3.0f%%", strings.Repeat("#", filled), strings.Repeat("-", progressBarWidth-filled), done, total, ratio*100)
+
+	if failed > 0 {
+		fmt.Fprintf(&b, " | %d failed", failed)
+	}
+
+	fmt.Fprintf(&b, " | %s", formatBytes(bytes))
+
+	if seconds := elapsed.Seconds(); seconds > 0 {
+		fmt.Fprintf(&b, " | %s/s", formatBytes(int64(float64(bytes)/seconds)))
+	}
+
+	if done > 0 && done < total {
+		eta := time.Duration(float64(elapsed) / float64(done) * float64(total-done))
+		fmt.Fprintf(&b, " | ETA %s", eta.Round(time.Second))
+	}
+
+	return b.String()
+}
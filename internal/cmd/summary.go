@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/brpaz/raindrop-images-dl/internal/downloader"
+)
+
+// printSummary prints a table with the totals of a download run, followed by the drops that failed.
+// For dry runs, the planned files and their totals are printed first.
+func printSummary(w io.Writer, result *downloader.Result) {
+	if result.DryRun {
+		printPlan(w, result.Plan)
+	}
+
+	downloadedLabel, sizeLabel := "Downloaded", "Size"
+	if result.DryRun {
+		downloadedLabel, sizeLabel = "Would download", "Estimated size"
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw)
+	fmt.Fprintf(tw, "%s\t%d\n", downloadedLabel, result.Downloaded)
+	fmt.Fprintf(tw, "Skipped\t%d\n", result.Skipped)
+	fmt.Fprintf(tw, "Failed\t%d\n", result.Failed)
+	fmt.Fprintf(tw, "%s\t%s\n", sizeLabel, formatBytes(result.Bytes))
+	fmt.Fprintf(tw, "Duration\t%s\n", result.Duration.Round(10*time.Millisecond))
+	_ = tw.Flush()
+
+	if len(result.Failures) > 0 {
+		fmt.Fprintln(w, "\nFailed drops:")
+		for _, failure := range result.Failures {
+			fmt.Fprintf(w, "  %d %q (%s): %s\n", failure.DropID, failure.Title, failure.Collection, failure.Reason)
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		fmt.Fprintln(w, "\nErrors:")
+		for _, err := range result.Errors {
+			fmt.Fprintf(w, "  %s\n", err)
+		}
+	}
+}
+
+// printPlan prints the files a dry run would change, followed by the number of files for each action
+// and the estimated size of the downloads
+func printPlan(w io.Writer, plan []downloader.PlannedFile) {
+	counts := make(map[downloader.PlanAction]int)
+	unknownSizes := 0
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, file := range plan {
+		counts[file.Action]++
+
+		size := ""
+		switch {
+		case file.Bytes < 0:
+			size = "unknown size"
+			unknownSizes++
+		case file.Action == downloader.PlanCreate || file.Action == downloader.PlanOverwrite:
+			size = formatBytes(file.Bytes)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", file.Action, file.Path, size)
+	}
+	_ = tw.Flush()
+
+	fmt.Fprintf(w, "\nPlan: %d to create, %d to overwrite, %d to skip, %d to prune\n",
+		counts[downloader.PlanCreate], counts[downloader.PlanOverwrite], counts[downloader.PlanSkip], counts[downloader.PlanPrune])
+
+	if unknownSizes > 0 {
+		fmt.Fprintf(w, "The size of %d files is unknown, and not included in the estimated size\n", unknownSizes)
+	}
+}
+
+// writeReport writes the result of a download run to a JSON file
+func writeReport(path string, result *downloader.Result) error {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the report: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write the report: %w", err)
+	}
+
+	return nil
+}
+
+// formatBytes formats a size in bytes with a binary unit, like "1.5 MiB"
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/cobra"
+)
+
+const (
+	FlagDownloadWatch    = "watch"
+	FlagDownloadInterval = "interval"
+	FlagDownloadSchedule = "schedule"
+)
+
+// DefaultWatchInterval is the time between two syncs in watch mode, when no interval or schedule is set
+const DefaultWatchInterval = time.Hour
+
+// watchSchedule returns the schedule of the syncs in watch mode, or nil when the command runs once
+func watchSchedule(cmd *cobra.Command) (cron.Schedule, error) {
+	watch, _ := cmd.Flags().GetBool(FlagDownloadWatch)
+	interval, _ := cmd.Flags().GetDuration(FlagDownloadInterval)
+	expression, _ := cmd.Flags().GetString(FlagDownloadSchedule)
+
+	if !watch {
+		return nil, nil
+	}
+
+	if expression != "" {
+		schedule, err := cron.ParseStandard(expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s cron expression %q: %w", FlagDownloadSchedule, expression, err)
+		}
+		return schedule, nil
+	}
+
+	if interval < time.Second {
+		return nil, fmt.Errorf("invalid --%s %s, it must be at least 1s", FlagDownloadInterval, interval)
+	}
+
+	return cron.Every(interval), nil
+}
+
+// watch runs sync now, then at each time of the schedule, until the context is cancelled, ex: on SIGTERM.
+// A sync starts only once the previous one is complete, so runs never overlap: when a sync takes longer
+// than the interval, the next one starts at the following scheduled time.
+// A failed sync is logged and retried at the next scheduled time, as the service must keep running.
+func watch(ctx context.Context, schedule cron.Schedule, sync func(ctx context.Context) error) error {
+	for {
+		err := sync(ctx)
+		if ctx.Err() != nil {
+			slog.Info("Stopped watching, the interrupted sync will resume on the next start")
+			return nil
+		}
+		if err != nil {
+			slog.Error("Sync failed", "error", err)
+		}
+
+		next := schedule.Next(time.Now())
+		slog.Info("Waiting for the next sync", "at", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			slog.Info("Stopped watching")
+			return nil
+		case <-timer.C:
+		}
+	}
+}
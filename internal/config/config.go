@@ -0,0 +1,143 @@
+// Package config loads the configuration file of the CLI, which describes the settings of the download
+// command in named profiles, ex: one for each backup job.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultProfileName is the profile used when none is selected
+const DefaultProfileName = "default"
+
+var (
+	ErrProfileNotFound = errors.New("profile not found")
+	ErrConfigNotFound  = errors.New("config file not found")
+)
+
+// Config is the content of the configuration file
+type Config struct {
+	// Path is the file the config was loaded from, empty when no file was found
+	Path string `yaml:"-"`
+	// DefaultProfile is the profile used when none is selected. Defaults to DefaultProfileName.
+	DefaultProfile string             `yaml:"default_profile,omitempty"`
+	Profiles       map[string]Profile `yaml:"profiles"`
+}
+
+// Profile holds the settings of the download command. Unset fields keep the value of the environment, or the default.
+type Profile struct {
+	// The API key is set with only one of APIKey, APIKeyFile or APIKeyCommand
+	APIKey        string `yaml:"api_key,omitempty"`
+	APIKeyFile    string `yaml:"api_key_file,omitempty"`
+	APIKeyCommand string `yaml:"api_key_command,omitempty"`
+
+	Collection int   `yaml:"collection,omitempty"`
+	All        *bool `yaml:"all,omitempty"`
+	Recursive  *bool `yaml:"recursive,omitempty"`
+	// Include and Exclude select collections by name or ID
+	Include []string `yaml:"include,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty"`
+
+	Output       string `yaml:"output,omitempty"`
+	GenInfoJSON  *bool  `yaml:"gen_info_json,omitempty"`
+	NameTemplate string `yaml:"name_template,omitempty"`
+	Source       string `yaml:"source,omitempty"`
+	AllMedia     *bool  `yaml:"all_media,omitempty"`
+	Concurrency  int    `yaml:"concurrency,omitempty"`
+	Mirror       *bool  `yaml:"mirror,omitempty"`
+	Prune        string `yaml:"prune,omitempty"`
+
+	Types  []string `yaml:"types,omitempty"`
+	Query  string   `yaml:"query,omitempty"`
+	Tags   []string `yaml:"tags,omitempty"`
+	Domain string   `yaml:"domain,omitempty"`
+	Since  string   `yaml:"since,omitempty"`
+	Until  string   `yaml:"until,omitempty"`
+
+	Progress string `yaml:"progress,omitempty"`
+	Report   string `yaml:"report,omitempty"`
+
+	// Watch keeps the command running, syncing every Interval, like "30m", or on the Schedule cron expression
+	Watch    *bool  `yaml:"watch,omitempty"`
+	Interval string `yaml:"interval,omitempty"`
+	Schedule string `yaml:"schedule,omitempty"`
+	// MetricsAddr is the address of the metrics and health endpoints, like ":9090"
+	MetricsAddr string `yaml:"metrics_addr,omitempty"`
+}
+
+// DefaultPath returns the path of the config file in the user config directory,
+// like "$XDG_CONFIG_HOME/raindrop-images-dl/config.yaml" on Linux
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "raindrop-images-dl", "config.yaml"), nil
+}
+
+// Load reads the config file at path. An empty path loads the file at DefaultPath, if it exists,
+// and an empty config otherwise. A file passed explicitly must exist.
+func Load(path string) (*Config, error) {
+	explicit := path != ""
+	if !explicit {
+		defaultPath, err := DefaultPath()
+		if err != nil {
+			return &Config{}, nil
+		}
+		path = defaultPath
+	}
+
+	data, err := os.ReadFile(path) // #nosec
+	if errors.Is(err, os.ErrNotExist) {
+		if explicit {
+			return nil, fmt.Errorf("%w: %s", ErrConfigNotFound, path)
+		}
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg := &Config{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode config file %s: %w", path, err)
+	}
+	cfg.Path = path
+
+	return cfg, nil
+}
+
+// Profile returns the profile with the given name, or the default profile when the name is empty.
+// A missing default profile results in an empty profile, while a missing named profile is an error.
+func (c *Config) Profile(name string) (Profile, string, error) {
+	explicit := name != ""
+	if !explicit {
+		name = c.DefaultProfile
+		explicit = name != ""
+	}
+	if name == "" {
+		name = DefaultProfileName
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok && explicit {
+		return Profile{}, name, fmt.Errorf("%w: %q, available profiles: %v", ErrProfileNotFound, name, c.ProfileNames())
+	}
+
+	return profile, name, nil
+}
+
+// ProfileNames returns the names of the profiles, sorted
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
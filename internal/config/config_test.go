@@ -0,0 +1,104 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/raindrop-images-dl/internal/config"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("WithExplicitPath_LoadsProfiles", func(t *testing.T) {
+		cfg, err := config.Load(filepath.Join("testdata", "config.yaml"))
+		require.NoError(t, err)
+
+		assert.Equal(t, filepath.Join("testdata", "config.yaml"), cfg.Path)
+		assert.Equal(t, []string{"everything", "memes"}, cfg.ProfileNames())
+
+		memes := cfg.Profiles["memes"]
+		assert.Equal(t, "test-key", memes.APIKey)
+		assert.Equal(t, 123, memes.Collection)
+		assert.Equal(t, 8, memes.Concurrency)
+		assert.Equal(t, []string{"reaction", "funny cats"}, memes.Tags)
+		require.NotNil(t, memes.GenInfoJSON)
+		assert.False(t, *memes.GenInfoJSON)
+		assert.Nil(t, memes.All)
+	})
+
+	t.Run("WithMissingExplicitPath_ReturnsError", func(t *testing.T) {
+		_, err := config.Load(filepath.Join(t.TempDir(), "config.yaml"))
+		assert.ErrorIs(t, err, config.ErrConfigNotFound)
+	})
+
+	t.Run("WithoutPath_LoadsFileFromUserConfigDir", func(t *testing.T) {
+		configHome := t.TempDir()
+		t.Setenv("XDG_CONFIG_HOME", configHome)
+
+		cfg, err := config.Load("")
+		require.NoError(t, err)
+		assert.Empty(t, cfg.Path)
+		assert.Empty(t, cfg.Profiles)
+
+		path := filepath.Join(configHome, "raindrop-images-dl", "config.yaml")
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+		require.NoError(t, os.WriteFile(path, []byte("profiles:\n  default:\n    output: /tmp\n"), 0o600))
+
+		cfg, err = config.Load("")
+		require.NoError(t, err)
+		assert.Equal(t, path, cfg.Path)
+		assert.Equal(t, "/tmp", cfg.Profiles["default"].Output)
+	})
+
+	t.Run("WithInvalidYAML_ReturnsError", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("profiles: [\n"), 0o600))
+
+		_, err := config.Load(path)
+		assert.ErrorContains(t, err, "failed to decode config file")
+	})
+}
+
+func TestConfig_Profile(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := config.Load(filepath.Join("testdata", "config.yaml"))
+	require.NoError(t, err)
+
+	t.Run("WithoutName_ReturnsDefaultProfile", func(t *testing.T) {
+		t.Parallel()
+
+		profile, name, err := cfg.Profile("")
+		require.NoError(t, err)
+		assert.Equal(t, "memes", name)
+		assert.Equal(t, "/backups/memes", profile.Output)
+	})
+
+	t.Run("WithName_ReturnsProfile", func(t *testing.T) {
+		t.Parallel()
+
+		profile, name, err := cfg.Profile("everything")
+		require.NoError(t, err)
+		assert.Equal(t, "everything", name)
+		assert.Equal(t, "/run/secrets/raindrop", profile.APIKeyFile)
+	})
+
+	t.Run("WithUnknownName_ReturnsError", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, err := cfg.Profile("work")
+		assert.ErrorIs(t, err, config.ErrProfileNotFound)
+	})
+
+	t.Run("WithoutDefaultProfile_ReturnsEmptyProfile", func(t *testing.T) {
+		t.Parallel()
+
+		profile, name, err := (&config.Config{}).Profile("")
+		require.NoError(t, err)
+		assert.Equal(t, config.DefaultProfileName, name)
+		assert.Equal(t, config.Profile{}, profile)
+	})
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrCredentialsNotFound = errors.New("credentials not found")
+	ErrInvalidProfileName  = errors.New("invalid profile name")
+)
+
+// Credentials are the OAuth2 credentials of a profile, saved by the login command.
+// The app client ID and secret are saved with the token, as they are needed to refresh it.
+type Credentials struct {
+	ClientID     string        `json:"client_id"`
+	ClientSecret string        `json:"client_secret"`
+	RedirectURL  string        `json:"redirect_url"`
+	Token        *oauth2.Token `json:"token"`
+}
+
+// CredentialStore saves the credentials of each profile in a file of its directory, only readable by the user
+type CredentialStore struct {
+	Dir string
+}
+
+// DefaultCredentialStore returns the store in the user config directory,
+// like "$XDG_CONFIG_HOME/raindrop-images-dl/credentials" on Linux
+func DefaultCredentialStore() (*CredentialStore, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return &CredentialStore{Dir: filepath.Join(dir, "raindrop-images-dl", "credentials")}, nil
+}
+
+// path returns the credentials file of a profile
+func (s *CredentialStore) path(profile string) (string, error) {
+	if profile == "" || profile == "." || profile == ".." || filepath.Base(profile) != profile {
+		return "", fmt.Errorf("%w: %q", ErrInvalidProfileName, profile)
+	}
+	return filepath.Join(s.Dir, profile+".json"), nil
+}
+
+// Load reads the credentials of a profile, returning ErrCredentialsNotFound if the profile never logged in
+func (s *CredentialStore) Load(profile string) (*Credentials, error) {
+	path, err := s.path(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path) // #nosec
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w for profile %q", ErrCredentialsNotFound, profile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	creds := &Credentials{}
+	if err := json.Unmarshal(data, creds); err != nil {
+		return nil, fmt.Errorf("failed to decode credentials of profile %q: %w", profile, err)
+	}
+
+	return creds, nil
+}
+
+// Save writes the credentials of a profile. The file is replaced atomically, so that a failed write does not lose the previous token.
+func (s *CredentialStore) Save(profile string, creds *Credentials) error {
+	path, err := s.path(profile)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode credentials: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.Dir, profile+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save credentials: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save credentials: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save credentials: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save credentials: %w", err)
+	}
+
+	return nil
+}
+
+// TokenSource returns a token source that saves the tokens refreshed by base in the credentials of the profile,
+// so that the next runs start with a valid token
+func (s *CredentialStore) TokenSource(profile string, creds *Credentials, base oauth2.TokenSource) oauth2.TokenSource {
+	saved := *creds
+	return &savingTokenSource{store: s, profile: profile, creds: saved, base: base}
+}
+
+type savingTokenSource struct {
+	store   *CredentialStore
+	profile string
+	base    oauth2.TokenSource
+
+	mu    sync.Mutex
+	creds Credentials
+}
+
+func (s *savingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := s.base.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.creds.Token == nil || s.creds.Token.AccessToken != token.AccessToken {
+		s.creds.Token = token
+		// The run can go on with the refreshed token, it is only requested again on the next run
+		if err := s.store.Save(s.profile, &s.creds); err != nil {
+			slog.Warn("Failed to save the refreshed token", "profile", s.profile, "error", err)
+		}
+	}
+
+	return token, nil
+}
@@ -0,0 +1,121 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/brpaz/raindrop-images-dl/internal/config"
+)
+
+func newTestCredentials(accessToken string) *config.Credentials {
+	return &config.Credentials{
+		ClientID:     "client-id",
+		ClientSecret: "client-secret",
+		RedirectURL:  "http://localhost:8765/callback",
+		Token: &oauth2.Token{
+			AccessToken:  accessToken,
+			RefreshToken: "refresh-token",
+			TokenType:    "Bearer",
+			Expiry:       time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+}
+
+// staticTokenSource returns a fixed token, as a refreshing token source would
+type staticTokenSource struct {
+	token *oauth2.Token
+	err   error
+}
+
+func (s staticTokenSource) Token() (*oauth2.Token, error) {
+	return s.token, s.err
+}
+
+func TestCredentialStore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("SaveAndLoad", func(t *testing.T) {
+		t.Parallel()
+
+		store := &config.CredentialStore{Dir: filepath.Join(t.TempDir(), "credentials")}
+		creds := newTestCredentials("access-1")
+
+		require.NoError(t, store.Save("work", creds))
+
+		info, err := os.Stat(filepath.Join(store.Dir, "work.json"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+		loaded, err := store.Load("work")
+		require.NoError(t, err)
+		assert.Equal(t, creds.ClientID, loaded.ClientID)
+		assert.Equal(t, creds.ClientSecret, loaded.ClientSecret)
+		assert.Equal(t, creds.RedirectURL, loaded.RedirectURL)
+		assert.Equal(t, "access-1", loaded.Token.AccessToken)
+		assert.Equal(t, "refresh-token", loaded.Token.RefreshToken)
+		assert.True(t, creds.Token.Expiry.Equal(loaded.Token.Expiry))
+	})
+
+	t.Run("Load_MissingProfile_ReturnsError", func(t *testing.T) {
+		t.Parallel()
+
+		store := &config.CredentialStore{Dir: t.TempDir()}
+
+		_, err := store.Load("work")
+		assert.ErrorIs(t, err, config.ErrCredentialsNotFound)
+	})
+
+	t.Run("InvalidProfileName_ReturnsError", func(t *testing.T) {
+		t.Parallel()
+
+		store := &config.CredentialStore{Dir: t.TempDir()}
+
+		for _, name := range []string{"", "..", "../work", "work/other"} {
+			assert.ErrorIs(t, store.Save(name, newTestCredentials("access-1")), config.ErrInvalidProfileName, name)
+		}
+	})
+
+	t.Run("TokenSource_SavesRefreshedTokens", func(t *testing.T) {
+		t.Parallel()
+
+		store := &config.CredentialStore{Dir: t.TempDir()}
+		creds := newTestCredentials("access-1")
+		require.NoError(t, store.Save("work", creds))
+
+		refreshed := &oauth2.Token{AccessToken: "access-2", RefreshToken: "refresh-token-2", TokenType: "Bearer"}
+		ts := store.TokenSource("work", creds, staticTokenSource{token: refreshed})
+
+		token, err := ts.Token()
+		require.NoError(t, err)
+		assert.Equal(t, "access-2", token.AccessToken)
+
+		loaded, err := store.Load("work")
+		require.NoError(t, err)
+		assert.Equal(t, "access-2", loaded.Token.AccessToken)
+		assert.Equal(t, "refresh-token-2", loaded.Token.RefreshToken)
+		assert.Equal(t, "client-id", loaded.ClientID)
+		// The credentials passed to the token source are left unchanged
+		assert.Equal(t, "access-1", creds.Token.AccessToken)
+	})
+
+	t.Run("TokenSource_ReturnsRefreshErrors", func(t *testing.T) {
+		t.Parallel()
+
+		store := &config.CredentialStore{Dir: t.TempDir()}
+		errRefresh := errors.New("refresh failed")
+		ts := store.TokenSource("work", newTestCredentials("access-1"), staticTokenSource{err: errRefresh})
+
+		_, err := ts.Token()
+		assert.ErrorIs(t, err, errRefresh)
+
+		_, err = store.Load("work")
+		assert.ErrorIs(t, err, config.ErrCredentialsNotFound)
+	})
+}
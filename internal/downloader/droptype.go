@@ -0,0 +1,45 @@
+package downloader
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+// DefaultTypes are the types of drops downloaded when none are configured
+var DefaultTypes = []raindrop.DropType{raindrop.DropTypeImage}
+
+// supportedTypes are the types of drops that can be downloaded
+var supportedTypes = map[raindrop.DropType]struct{}{
+	raindrop.DropTypeImage:    {},
+	raindrop.DropTypeVideo:    {},
+	raindrop.DropTypeDocument: {},
+	raindrop.DropTypeAudio:    {},
+	raindrop.DropTypeArticle:  {},
+}
+
+// ParseTypes converts type names (ex: "image", "video") into drop types, ignoring duplicates
+func ParseTypes(names []string) ([]raindrop.DropType, error) {
+	types := make([]raindrop.DropType, 0, len(names))
+	seen := make(map[raindrop.DropType]struct{}, len(names))
+
+	for _, name := range names {
+		dropType := raindrop.DropType(strings.ToLower(strings.TrimSpace(name)))
+		if _, ok := supportedTypes[dropType]; !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidType, name)
+		}
+
+		if _, ok := seen[dropType]; ok {
+			continue
+		}
+		seen[dropType] = struct{}{}
+		types = append(types, dropType)
+	}
+
+	if len(types) == 0 {
+		return nil, fmt.Errorf("%w: no type selected", ErrInvalidType)
+	}
+
+	return types, nil
+}
@@ -0,0 +1,132 @@
+package downloader_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/raindrop-images-dl/internal/downloader"
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+func TestParseTypes(t *testing.T) {
+	t.Parallel()
+
+	types, err := downloader.ParseTypes([]string{"image", " Video ", "image", "document"})
+	require.NoError(t, err)
+	assert.Equal(t, []raindrop.DropType{raindrop.DropTypeImage, raindrop.DropTypeVideo, raindrop.DropTypeDocument}, types)
+
+	_, err = downloader.ParseTypes([]string{"image", "podcast"})
+	assert.ErrorIs(t, err, downloader.ErrInvalidType)
+
+	_, err = downloader.ParseTypes(nil)
+	assert.ErrorIs(t, err, downloader.ErrInvalidType)
+}
+
+func TestDownloader_Types(t *testing.T) {
+	t.Parallel()
+
+	mp4Bytes := []byte{0x00, 0x00, 0x00, 0x18, 'f', 't', 'y', 'p', 'm', 'p', '4', '2', 0x00, 0x00}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/clip.mp4":
+			_, _ = w.Write(mp4Bytes)
+		case "/paper.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			_, _ = w.Write([]byte("%PDF-1.7\n"))
+		case "/watch":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte("<html><body>video page</body></html>"))
+		default:
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(pngBytes)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	collectionID := 123
+	drops := map[raindrop.DropType][]raindrop.Drop{
+		raindrop.DropTypeImage: {
+			{ID: 1, Title: "Photo", Type: raindrop.DropTypeImage, Cover: server.URL + "/photo.png", Link: server.URL + "/watch"},
+		},
+		raindrop.DropTypeVideo: {
+			{ID: 2, Title: "Clip", Type: raindrop.DropTypeVideo, Cover: server.URL + "/clip-thumb.png", Link: server.URL + "/clip.mp4"},
+			{ID: 3, Title: "Stream", Type: raindrop.DropTypeVideo, Cover: server.URL + "/stream-thumb.png", Link: server.URL + "/watch"},
+		},
+		raindrop.DropTypeDocument: {
+			{ID: 4, Title: "Paper", Type: raindrop.DropTypeDocument, Cover: server.URL + "/paper-thumb.png", Link: server.URL + "/paper.pdf"},
+		},
+		raindrop.DropTypeArticle: {
+			{ID: 5, Title: "Story", Type: raindrop.DropTypeArticle, Cover: server.URL + "/story.png", Link: server.URL + "/watch"},
+		},
+	}
+
+	setup := func(t *testing.T, types ...raindrop.DropType) (*downloader.Downloader, *MockRaindropClient) {
+		t.Helper()
+
+		rdClient := &MockRaindropClient{}
+		dl, err := downloader.NewDownloader(
+			downloader.WithRaindropClient(rdClient),
+			downloader.WithTypes(types...),
+		)
+		require.NoError(t, err)
+
+		rdClient.On("GetCollectionByID", mock.Anything, collectionID).Return(&raindrop.CollectionItem{
+			ID:    int64(collectionID),
+			Title: "Media",
+		}, nil)
+		rdClient.On("GetPermanentCopyURL", mock.Anything, mock.Anything).Return("", raindrop.ErrNotFound).Maybe()
+		for dropType, items := range drops {
+			rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, raindrop.ListOptions{Search: raindrop.NewSearch().Type(dropType)}).Return(&raindrop.DropsPage{
+				Items: items,
+			}, nil).Maybe()
+		}
+
+		return dl, rdClient
+	}
+
+	t.Run("ByDefault_OnlyDownloadsImages", func(t *testing.T) {
+		t.Parallel()
+
+		dl, rdClient := setup(t, downloader.DefaultTypes...)
+		outputDir := t.TempDir()
+
+		mustDownloadCollection(t, dl, context.Background(), collectionID, outputDir, false)
+
+		assert.FileExists(t, filepath.Join(outputDir, "Media", "1-photo.png"))
+		rdClient.AssertNumberOfCalls(t, "GetDropsFromCollection", 1)
+	})
+
+	t.Run("WithTypes_DownloadsEachTypeFromItsSource", func(t *testing.T) {
+		t.Parallel()
+
+		dl, _ := setup(t, raindrop.DropTypeVideo, raindrop.DropTypeDocument, raindrop.DropTypeArticle)
+		outputDir := t.TempDir()
+
+		mustDownloadCollection(t, dl, context.Background(), collectionID, outputDir, false)
+
+		// Files are saved from the link, falling back to the cover when the link is a web page
+		assert.FileExists(t, filepath.Join(outputDir, "Media", "2-clip.mp4"))
+		assert.FileExists(t, filepath.Join(outputDir, "Media", "3-stream.png"))
+		assert.FileExists(t, filepath.Join(outputDir, "Media", "4-paper.pdf"))
+		assert.FileExists(t, filepath.Join(outputDir, "Media", "5-story.png"))
+		assert.NoFileExists(t, filepath.Join(outputDir, "Media", "1-photo.png"))
+	})
+
+	t.Run("WithInvalidType_ReturnsError", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := downloader.NewDownloader(
+			downloader.WithRaindropClient(&MockRaindropClient{}),
+			downloader.WithTypes("podcast"),
+		)
+		assert.ErrorIs(t, err, downloader.ErrInvalidType)
+	})
+}
@@ -0,0 +1,349 @@
+package downloader
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// partSuffix is appended to the path of files while they are downloaded.
+// Files are only renamed to their final path once complete, so an interrupted download
+// never leaves a truncated file that would be considered as already downloaded.
+const partSuffix = ".part"
+
+// partInfoSuffix is appended to the path of the partial file for its resume info
+const partInfoSuffix = ".json"
+
+var ErrIncompleteDownload = errors.New("incomplete download")
+
+// downloadedFile describes a file saved by downloadFile
+type downloadedFile struct {
+	URL      string
+	Path     string
+	Checksum string
+	Size     int64
+	// Existed is true when the file was already present and was left untouched
+	Existed bool
+}
+
+// DownloadFile downloads a file from a URL and saves it to the destination path.
+// The file extension is appended to dest based on the detected media type.
+// The file is written to a ".part" file first, which is resumed with a Range request if a previous download was interrupted.
+// The resume is conditional, so that a file that changed since is downloaded again instead of being appended to the old one.
+// A file already at the destination is kept, unless it is one of the replaced files, which is overwritten once the new file is complete.
+func downloadFile(ctx context.Context, mediaTypes MediaTypes, url, dest string, replaced fileSet) (*downloadedFile, error) {
+	partPath := dest + partSuffix
+
+	resp, offset, err := requestFile(ctx, url, partPath)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body := bufio.NewReaderSize(resp.Body, sniffLen)
+	head, err := fileHead(partPath, offset, body)
+	if err != nil {
+		return nil, err
+	}
+
+	extension, err := mediaTypes.extension(resp.Header.Get("Content-Type"), head, url)
+	if err != nil {
+		return nil, err
+	}
+
+	dest += extension
+
+	if !replaced[dest] && fileExists(dest) {
+		slog.Info("File already exists, skipping", "path", dest)
+		removePart(partPath)
+		return existingFile(url, dest)
+	}
+
+	checksum, size, err := writePart(partPath, offset, body, expectedSize(resp, offset))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Rename(partPath, dest); err != nil {
+		return nil, err
+	}
+	_ = os.Remove(partPath + partInfoSuffix)
+
+	return &downloadedFile{
+		URL:      url,
+		Path:     dest,
+		Checksum: checksum,
+		Size:     size,
+	}, nil
+}
+
+// requestFile requests a file, resuming from the end of the partial file if it exists.
+// It returns the response and the offset its body starts at, which is zero if the download starts over.
+func requestFile(ctx context.Context, url, partPath string) (*http.Response, int64, error) {
+	var offset int64
+	var info partInfo
+	if stat, err := os.Stat(partPath); err == nil && stat.Size() > 0 {
+		// A partial file can only be resumed if it was downloaded from the same URL, and the remote file can be validated
+		if info = readPartInfo(partPath); info.URL == url && info.validator() != "" {
+			offset = stat.Size()
+		} else {
+			removePart(partPath)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", info.validator())
+	}
+
+	resp, err := http.DefaultClient.Do(req) // #nosec
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// The server does not support ranges or the file changed, so the download starts over
+		if err := writePartInfo(partPath, url, resp); err != nil {
+			resp.Body.Close()
+			return nil, 0, err
+		}
+		return resp, 0, nil
+	case http.StatusPartialContent:
+		if start, _, ok := parseContentRange(resp.Header.Get("Content-Range")); ok && start == offset {
+			slog.Info("Resuming download", "url", url, "offset", offset)
+			return resp, offset, nil
+		}
+		resp.Body.Close()
+		removePart(partPath)
+		return nil, 0, fmt.Errorf("failed to resume download: unexpected content range %q", resp.Header.Get("Content-Range"))
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file does not match the remote file anymore, so it is discarded
+		resp.Body.Close()
+		if offset == 0 {
+			return nil, 0, fmt.Errorf("failed to download file: %s", resp.Status)
+		}
+		removePart(partPath)
+		return requestFile(ctx, url, partPath)
+	default:
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("failed to download file: %s", resp.Status)
+	}
+}
+
+// partInfo is saved next to a partial file, to check that the remote file did not change before resuming it
+type partInfo struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// validator returns the If-Range value identifying the remote file, or an empty string if it cannot be validated.
+// Weak ETags cannot be used in If-Range, so Last-Modified is used instead.
+func (i partInfo) validator() string {
+	if i.ETag != "" && !strings.HasPrefix(i.ETag, "W/") {
+		return i.ETag
+	}
+	return i.LastModified
+}
+
+// readPartInfo reads the resume info of a partial file, returning an empty info if it is missing or invalid
+func readPartInfo(partPath string) partInfo {
+	var info partInfo
+	data, err := os.ReadFile(partPath + partInfoSuffix) // #nosec
+	if err == nil {
+		_ = json.Unmarshal(data, &info)
+	}
+	return info
+}
+
+// writePartInfo saves the resume info of a download starting over, from the validators of the response.
+// Nothing is saved when the response has no validator, as the download could not be resumed safely.
+func writePartInfo(partPath, url string, resp *http.Response) error {
+	info := partInfo{
+		URL:          url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	if info.validator() == "" {
+		_ = os.Remove(partPath + partInfoSuffix)
+		return nil
+	}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(partPath+partInfoSuffix, data, 0o600)
+}
+
+// removePart removes a partial file and its resume info
+func removePart(partPath string) {
+	_ = os.Remove(partPath)
+	_ = os.Remove(partPath + partInfoSuffix)
+}
+
+// fileHead returns the first bytes of the file, read from the partial file and the response body
+func fileHead(partPath string, offset int64, body *bufio.Reader) ([]byte, error) {
+	var head []byte
+
+	if offset > 0 {
+		part, err := os.Open(partPath) // #nosec
+		if err != nil {
+			return nil, err
+		}
+		defer part.Close()
+
+		head = make([]byte, min(offset, sniffLen))
+		if _, err := io.ReadFull(part, head); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(head) < sniffLen {
+		rest, err := body.Peek(sniffLen - len(head))
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		head = append(head, rest...)
+	}
+
+	return head, nil
+}
+
+// writePart writes the body to the partial file, appending to it when offset is not zero.
+// The file is synced to disk and its size is verified against the expected size, if known.
+// It returns the checksum and size of the complete file.
+func writePart(partPath string, offset int64, body io.Reader, expectedSize int64) (string, int64, error) {
+	flags := os.O_RDWR | os.O_CREATE | os.O_TRUNC
+	if offset > 0 {
+		flags = os.O_RDWR | os.O_APPEND
+	}
+
+	out, err := os.OpenFile(partPath, flags, 0o666) // #nosec
+	if err != nil {
+		return "", 0, err
+	}
+	defer out.Close()
+
+	hash := sha256.New()
+	if err := hashPart(hash, out, offset); err != nil {
+		return "", 0, err
+	}
+
+	written, err := io.Copy(io.MultiWriter(out, hash), body)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if err := out.Sync(); err != nil {
+		return "", 0, err
+	}
+
+	size := offset + written
+	if expectedSize >= 0 && size != expectedSize {
+		return "", 0, fmt.Errorf("%w: got %d bytes, expected %d", ErrIncompleteDownload, size, expectedSize)
+	}
+
+	if err := out.Close(); err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// hashPart adds the first n bytes of the partial file to the hash
+func hashPart(h hash.Hash, part *os.File, n int64) error {
+	if n == 0 {
+		return nil
+	}
+
+	_, err := io.Copy(h, io.NewSectionReader(part, 0, n))
+	return err
+}
+
+// expectedSize returns the size of the complete file according to the response headers, or -1 if unknown
+func expectedSize(resp *http.Response, offset int64) int64 {
+	if resp.StatusCode == http.StatusPartialContent {
+		if _, total, ok := parseContentRange(resp.Header.Get("Content-Range")); ok && total >= 0 {
+			return total
+		}
+	}
+
+	if resp.ContentLength < 0 {
+		return -1
+	}
+	return offset + resp.ContentLength
+}
+
+// parseContentRange parses a "bytes start-end/total" Content-Range header.
+// The total is -1 if unknown.
+func parseContentRange(header string) (start, total int64, ok bool) {
+	rangeSpec, found := strings.CutPrefix(header, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+
+	byteRange, size, found := strings.Cut(rangeSpec, "/")
+	if !found {
+		return 0, 0, false
+	}
+
+	startStr, _, found := strings.Cut(byteRange, "-")
+	if !found {
+		return 0, 0, false
+	}
+
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	total = -1
+	if size != "*" {
+		if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+
+	return start, total, true
+}
+
+// existingFile describes a file already present on disk, computing its checksum.
+func existingFile(url, path string) (*downloadedFile, error) {
+	f, err := os.Open(path) // #nosec
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return nil, err
+	}
+
+	return &downloadedFile{
+		URL:      url,
+		Path:     path,
+		Checksum: hex.EncodeToString(hash.Sum(nil)),
+		Size:     size,
+		Existed:  true,
+	}, nil
+}
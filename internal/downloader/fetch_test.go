@@ -0,0 +1,199 @@
+package downloader_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/raindrop-images-dl/internal/downloader"
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+func TestDownloader_FileWrites(t *testing.T) {
+	t.Parallel()
+
+	collectionID := 123
+	content := append(append([]byte{}, pngBytes...), bytes.Repeat([]byte{0xab}, 4096)...)
+
+	// setup returns a downloader for a single drop named "image", whose cover is served by the handler
+	setup := func(t *testing.T, handler http.HandlerFunc) (*downloader.Downloader, string) {
+		t.Helper()
+
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+
+		nameTmpl, err := downloader.ParseNameTemplate("{{.Title}}")
+		require.NoError(t, err)
+
+		rdClient := &MockRaindropClient{}
+		dl, err := downloader.NewDownloader(
+			downloader.WithRaindropClient(rdClient),
+			downloader.WithNameTemplate(nameTmpl),
+		)
+		require.NoError(t, err)
+
+		rdClient.On("GetCollectionByID", mock.Anything, collectionID).Return(&raindrop.CollectionItem{
+			ID: int64(collectionID),
+		}, nil)
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(&raindrop.DropsPage{
+			Items: []raindrop.Drop{{ID: 1, Title: "image", Cover: server.URL + "/image.png"}},
+		}, nil)
+
+		return dl, t.TempDir()
+	}
+
+	// interrupted returns a handler that sends the first 1000 bytes of the content on its first request,
+	// as if the download was interrupted, and serves the content of the next requests with the next handler
+	interrupted := func(etag string, next http.HandlerFunc) http.HandlerFunc {
+		var mu sync.Mutex
+		var requests int
+
+		return func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			requests++
+			first := requests == 1
+			mu.Unlock()
+
+			if !first {
+				next(w, r)
+				return
+			}
+
+			w.Header().Set("Content-Type", "image/png")
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			_, _ = w.Write(content[:1000])
+		}
+	}
+
+	// downloadTwice downloads the collection a first time, which is interrupted, and then a second time
+	downloadTwice := func(t *testing.T, dl *downloader.Downloader, outputDir string) {
+		t.Helper()
+
+		_, err := dl.DownloadCollection(context.Background(), collectionID, outputDir, false)
+		require.ErrorIs(t, err, downloader.ErrPartialFailure)
+		require.FileExists(t, filepath.Join(outputDir, "image.part"))
+
+		mustDownloadCollection(t, dl, context.Background(), collectionID, outputDir, false)
+	}
+
+	t.Run("ResumesPartialDownload", func(t *testing.T) {
+		t.Parallel()
+
+		var mu sync.Mutex
+		var rangeHeader, ifRangeHeader string
+
+		dl, outputDir := setup(t, interrupted(`"v1"`, func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			rangeHeader = r.Header.Get("Range")
+			ifRangeHeader = r.Header.Get("If-Range")
+			mu.Unlock()
+
+			w.Header().Set("Content-Type", "image/png")
+			w.Header().Set("ETag", `"v1"`)
+			http.ServeContent(w, r, "image.png", time.Time{}, bytes.NewReader(content))
+		}))
+
+		downloadTwice(t, dl, outputDir)
+
+		assert.Equal(t, "bytes=1000-", rangeHeader)
+		assert.Equal(t, `"v1"`, ifRangeHeader)
+		assert.NoFileExists(t, filepath.Join(outputDir, "image.part"))
+		assert.NoFileExists(t, filepath.Join(outputDir, "image.part.json"))
+
+		saved, err := os.ReadFile(filepath.Join(outputDir, "image.png"))
+		require.NoError(t, err)
+		assert.Equal(t, content, saved)
+	})
+
+	t.Run("RestartsWhenTheFileChanged", func(t *testing.T) {
+		t.Parallel()
+
+		changed := append(append([]byte{}, pngBytes...), bytes.Repeat([]byte{0xcd}, 4096)...)
+
+		dl, outputDir := setup(t, interrupted(`"v1"`, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			w.Header().Set("ETag", `"v2"`)
+			http.ServeContent(w, r, "image.png", time.Time{}, bytes.NewReader(changed))
+		}))
+
+		downloadTwice(t, dl, outputDir)
+
+		saved, err := os.ReadFile(filepath.Join(outputDir, "image.png"))
+		require.NoError(t, err)
+		assert.Equal(t, changed, saved)
+	})
+
+	t.Run("RestartsWithoutResumeInfo", func(t *testing.T) {
+		t.Parallel()
+
+		var mu sync.Mutex
+		var rangeHeader string
+
+		dl, outputDir := setup(t, func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			rangeHeader = r.Header.Get("Range")
+			mu.Unlock()
+
+			w.Header().Set("Content-Type", "image/png")
+			w.Header().Set("ETag", `"v1"`)
+			http.ServeContent(w, r, "image.png", time.Time{}, bytes.NewReader(content))
+		})
+
+		// A partial file left by another URL, or by a previous version, cannot be validated
+		require.NoError(t, os.WriteFile(filepath.Join(outputDir, "image.part"), []byte("stale"), 0o600))
+
+		mustDownloadCollection(t, dl, context.Background(), collectionID, outputDir, false)
+
+		assert.Empty(t, rangeHeader)
+
+		saved, err := os.ReadFile(filepath.Join(outputDir, "image.png"))
+		require.NoError(t, err)
+		assert.Equal(t, content, saved)
+	})
+
+	t.Run("RestartsWhenRangeIsNotSupported", func(t *testing.T) {
+		t.Parallel()
+
+		dl, outputDir := setup(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(content)
+		})
+
+		require.NoError(t, os.WriteFile(filepath.Join(outputDir, "image.part"), []byte("stale"), 0o600))
+
+		mustDownloadCollection(t, dl, context.Background(), collectionID, outputDir, false)
+
+		saved, err := os.ReadFile(filepath.Join(outputDir, "image.png"))
+		require.NoError(t, err)
+		assert.Equal(t, content, saved)
+	})
+
+	t.Run("KeepsPartialFileWhenTruncated", func(t *testing.T) {
+		t.Parallel()
+
+		dl, outputDir := setup(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			w.Header().Set("Content-Length", "5000")
+			_, _ = w.Write(content[:1000])
+		})
+
+		result, err := dl.DownloadCollection(context.Background(), collectionID, outputDir, false)
+		require.ErrorIs(t, err, downloader.ErrPartialFailure)
+		assert.Equal(t, 1, result.Failed)
+
+		assert.NoFileExists(t, filepath.Join(outputDir, "image.png"))
+		assert.FileExists(t, filepath.Join(outputDir, "image.part"))
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
@@ -16,23 +17,21 @@ type InfoFile struct {
 	Tags        []string  `json:"tags"`
 	CreatedAt   time.Time `json:"created_at"`
 	OriginalURL string    `json:"original_url"`
+	// Files are the names of the files saved for the drop
+	Files []string `json:"files,omitempty"`
 }
 
-// createInfoFile generates a metadata file for a given Raindrop bookmark.
-func createInfoFile(baseFilePath string, bookmark raindrop.Drop) error {
+// createInfoFile generates a metadata file for a given Raindrop bookmark, listing the files saved for it.
+// An existing file is only replaced when overwrite is set, ex: when the drop changed. It is replaced atomically,
+// so that an interrupted write does not leave a truncated file.
+func createInfoFile(baseFilePath string, bookmark raindrop.Drop, files []string, overwrite bool) error {
 	infoFilePath := fmt.Sprintf("%s.info.json", baseFilePath)
 
-	if fileExists(infoFilePath) {
+	if !overwrite && fileExists(infoFilePath) {
 		slog.Info("Info file already exists, skipping", "path", infoFilePath)
 		return nil
 	}
 
-	infoFile, err := os.Create(infoFilePath)
-	if err != nil {
-		return err
-	}
-	defer infoFile.Close()
-
 	info := InfoFile{
 		Title:       bookmark.Title,
 		Description: bookmark.GetDescription(),
@@ -41,5 +40,24 @@ func createInfoFile(baseFilePath string, bookmark raindrop.Drop) error {
 		OriginalURL: bookmark.Link,
 	}
 
-	return json.NewEncoder(infoFile).Encode(info)
+	for _, file := range files {
+		info.Files = append(info.Files, filepath.Base(file))
+	}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := infoFilePath + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), 0o666); err != nil { // #nosec
+		return err
+	}
+
+	if err := os.Rename(tmpPath, infoFilePath); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
 }
@@ -0,0 +1,183 @@
+package downloader
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// sniffLen is the number of bytes read from the start of a file to detect its type
+const sniffLen = 512
+
+// MediaTypes maps media types (ex: "image/jpeg") to the extension used to save files of that type
+type MediaTypes map[string]string
+
+// defaultMediaTypes are the media types supported out of the box
+var defaultMediaTypes = MediaTypes{
+	// Images
+	"image/jpeg":    ".jpg",
+	"image/png":     ".png",
+	"image/gif":     ".gif",
+	"image/bmp":     ".bmp",
+	"image/webp":    ".webp",
+	"image/svg+xml": ".svg",
+	"image/avif":    ".avif",
+	"image/heic":    ".heic",
+	"image/heif":    ".heif",
+	"image/tiff":    ".tiff",
+	"image/x-icon":  ".ico",
+
+	// Videos
+	"video/mp4":        ".mp4",
+	"video/webm":       ".webm",
+	"video/quicktime":  ".mov",
+	"video/ogg":        ".ogv",
+	"video/x-matroska": ".mkv",
+	"video/mpeg":       ".mpeg",
+	"video/avi":        ".avi",
+	"video/x-msvideo":  ".avi",
+
+	// Audio
+	"audio/mpeg":      ".mp3",
+	"audio/mp4":       ".m4a",
+	"audio/aac":       ".aac",
+	"audio/ogg":       ".ogg",
+	"application/ogg": ".ogg",
+	"audio/opus":      ".opus",
+	"audio/flac":      ".flac",
+	"audio/wave":      ".wav",
+	"audio/wav":       ".wav",
+	"audio/x-wav":     ".wav",
+	"audio/webm":      ".weba",
+
+	// Documents
+	"application/pdf":               ".pdf",
+	"application/epub+zip":          ".epub",
+	"application/msword":            ".doc",
+	"application/vnd.ms-excel":      ".xls",
+	"application/vnd.ms-powerpoint": ".ppt",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   ".docx",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         ".xlsx",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": ".pptx",
+	"application/vnd.oasis.opendocument.text":                                   ".odt",
+	"application/rtf": ".rtf",
+	"text/csv":        ".csv",
+	"text/markdown":   ".md",
+	"text/plain":      ".txt",
+}
+
+// genericMediaTypes are sent by servers that do not know the file type, so the content is sniffed instead
+var genericMediaTypes = map[string]struct{}{
+	"":                         {},
+	"application/octet-stream": {},
+	"binary/octet-stream":      {},
+	"application/binary":       {},
+	"application/unknown":      {},
+}
+
+// genericExtension is used for files of a generic media type whose real type could not be detected
+const genericExtension = ".bin"
+
+// clone returns a copy of the media types, so it can be extended without affecting the original
+func (m MediaTypes) clone() MediaTypes {
+	clone := make(MediaTypes, len(m))
+	for mediaType, ext := range m {
+		clone[mediaType] = ext
+	}
+	return clone
+}
+
+// extension returns the extension for a downloaded file. The type is detected from the first bytes
+// of the file, then from the Content-Type header and finally from the URL extension.
+func (m MediaTypes) extension(contentType string, head []byte, rawURL string) (string, error) {
+	if ext, ok := m[sniff(head)]; ok {
+		return ext, nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = ""
+	}
+
+	if ext, ok := m[mediaType]; ok {
+		return ext, nil
+	}
+
+	if ext, ok := m.urlExtension(rawURL); ok {
+		return ext, nil
+	}
+
+	if _, generic := genericMediaTypes[mediaType]; generic {
+		return genericExtension, nil
+	}
+
+	return "", fmt.Errorf("unsupported content type: %s", contentType)
+}
+
+// urlExtension returns the extension of the URL path, if it matches a supported media type
+func (m MediaTypes) urlExtension(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+
+	ext := strings.ToLower(path.Ext(u.Path))
+	if ext == "" {
+		return "", false
+	}
+
+	for _, supported := range m {
+		if supported == ext {
+			return supported, true
+		}
+	}
+
+	// Look up aliases, like ".jpeg" for ".jpg"
+	mediaType, _, _ := mime.ParseMediaType(mime.TypeByExtension(ext))
+	supported, ok := m[mediaType]
+	return supported, ok
+}
+
+// sniff detects the media type of a file from its first bytes.
+// It extends http.DetectContentType with formats it does not recognize, like ISO media files (AVIF, HEIC, MP4, M4A), TIFF and SVG.
+func sniff(head []byte) string {
+	if len(head) >= 12 && string(head[4:8]) == "ftyp" {
+		switch string(head[8:12]) {
+		case "avif", "avis":
+			return "image/avif"
+		case "heic", "heix", "heim", "heis", "hevc", "hevx":
+			return "image/heic"
+		case "mif1", "msf1":
+			return "image/heif"
+		case "qt  ":
+			return "video/quicktime"
+		case "M4A ", "M4B ":
+			return "audio/mp4"
+		default:
+			return "video/mp4"
+		}
+	}
+
+	if bytes.HasPrefix(head, []byte("II*\x00")) || bytes.HasPrefix(head, []byte("MM\x00*")) {
+		return "image/tiff"
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+
+	if mediaType == "text/xml" || mediaType == "text/plain" {
+		if bytes.Contains(bytes.ToLower(head), []byte("<svg")) {
+			return "image/svg+xml"
+		}
+	}
+
+	// Text is too ambiguous to be detected reliably, so the Content-Type header is preferred
+	if strings.HasPrefix(mediaType, "text/") {
+		return ""
+	}
+
+	return mediaType
+}
@@ -0,0 +1,118 @@
+package downloader_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/raindrop-images-dl/internal/downloader"
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+func TestDownloader_MediaTypes(t *testing.T) {
+	t.Parallel()
+
+	jpegBytes := []byte{0xff, 0xd8, 0xff, 0xe0, 0x00, 0x10, 'J', 'F', 'I', 'F', 0x00}
+	avifBytes := []byte{0x00, 0x00, 0x00, 0x1c, 'f', 't', 'y', 'p', 'a', 'v', 'i', 'f', 0x00, 0x00}
+	mp4Bytes := []byte{0x00, 0x00, 0x00, 0x18, 'f', 't', 'y', 'p', 'm', 'p', '4', '2', 0x00, 0x00}
+	tiffBytes := []byte{'I', 'I', '*', 0x00, 0x08, 0x00}
+	m4aBytes := []byte{0x00, 0x00, 0x00, 0x18, 'f', 't', 'y', 'p', 'M', '4', 'A', ' ', 0x00, 0x00}
+	mp3Bytes := []byte{'I', 'D', '3', 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	zipBytes := []byte{'P', 'K', 0x03, 0x04, 0x14, 0x00, 0x06, 0x00}
+	unknownBytes := []byte{0x01, 0x02, 0x03, 0x04}
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        []byte
+		// expected is the extension of the saved file, empty if the download must fail
+		expected string
+	}{
+		{name: "ContentTypeWithParameters", path: "/a", contentType: "image/jpeg; charset=binary", body: jpegBytes, expected: ".jpg"},
+		{name: "GenericContentType", path: "/b", contentType: "application/octet-stream", body: pngBytes, expected: ".png"},
+		{name: "WrongContentType", path: "/c", contentType: "image/jpeg", body: pngBytes, expected: ".png"},
+		{name: "AVIF", path: "/d", contentType: "binary/octet-stream", body: avifBytes, expected: ".avif"},
+		{name: "MP4", path: "/e", contentType: "video/mp4", body: mp4Bytes, expected: ".mp4"},
+		{name: "TIFF", path: "/f", contentType: "", body: tiffBytes, expected: ".tiff"},
+		{name: "M4A", path: "/m4a", contentType: "application/octet-stream", body: m4aBytes, expected: ".m4a"},
+		{name: "MP3", path: "/mp3", contentType: "", body: mp3Bytes, expected: ".mp3"},
+		{name: "PDF", path: "/pdf", contentType: "application/octet-stream", body: []byte("%PDF-1.7\n"), expected: ".pdf"},
+		{name: "OfficeDocument", path: "/docx", contentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document", body: zipBytes, expected: ".docx"},
+		{name: "URLExtension", path: "/photo.HEIC", contentType: "application/octet-stream", body: unknownBytes, expected: ".heic"},
+		{name: "UnknownGenericContent", path: "/g", contentType: "application/octet-stream", body: unknownBytes, expected: ".bin"},
+		{name: "CustomMediaType", path: "/h", contentType: "image/jxl", body: unknownBytes, expected: ".jxl"},
+		{name: "Unsupported", path: "/i", contentType: "text/html", body: []byte("<html></html>"), expected: ""},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, tt := range tests {
+			if tt.path == r.URL.Path {
+				w.Header().Set("Content-Type", tt.contentType)
+				_, _ = w.Write(tt.body)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	nameTmpl, err := downloader.ParseNameTemplate("{{.Title}}")
+	require.NoError(t, err)
+
+	rdClient := &MockRaindropClient{}
+	dl, err := downloader.NewDownloader(
+		downloader.WithRaindropClient(rdClient),
+		downloader.WithNameTemplate(nameTmpl),
+		downloader.WithMediaType("image/JXL", ".jxl"),
+	)
+	require.NoError(t, err)
+
+	collectionID := 123
+	rdClient.On("GetCollectionByID", mock.Anything, collectionID).Return(&raindrop.CollectionItem{
+		ID: int64(collectionID),
+	}, nil)
+
+	var drops []raindrop.Drop
+	for i, tt := range tests {
+		drops = append(drops, raindrop.Drop{ID: int64(i + 1), Title: tt.name, Cover: server.URL + tt.path})
+	}
+	rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(&raindrop.DropsPage{
+		Items: drops,
+	}, nil)
+
+	outputDir := t.TempDir()
+
+	// The unsupported file fails, but does not prevent the others from being saved
+	result, err := dl.DownloadCollection(context.Background(), collectionID, outputDir, false)
+	require.ErrorIs(t, err, downloader.ErrPartialFailure)
+	assert.Equal(t, 1, result.Failed)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			matches, err := filepath.Glob(filepath.Join(outputDir, tt.name+".*"))
+			require.NoError(t, err)
+
+			if tt.expected == "" {
+				assert.Empty(t, matches)
+				return
+			}
+
+			require.Len(t, matches, 1)
+			assert.Equal(t, tt.expected, filepath.Ext(matches[0]))
+
+			content, err := os.ReadFile(matches[0])
+			require.NoError(t, err)
+			assert.Equal(t, tt.body, content)
+		})
+	}
+}
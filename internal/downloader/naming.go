@@ -0,0 +1,102 @@
+package downloader
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/brpaz/raindrop-images-dl/internal/filename"
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+// DefaultNameTemplate is the template used to name the saved files when none is configured.
+// It starts with the drop ID, so that two drops never get the same name.
+const DefaultNameTemplate = "{{.ID}}-{{.Title | slug}}"
+
+// nameSeparators are the characters trimmed from the edges of the generated names
+const nameSeparators = " -_."
+
+// NameTemplate generates the name of the files saved for a drop, without extension.
+// It is a Go text/template executed over the raindrop.Drop, with the following extra functions:
+//   - slug: converts a string into a lowercase name made of letters, digits and dashes
+//   - lower, upper: change the case of a string
+//   - first: returns the first element of a list, like the tags, or an empty string if the list is empty
+//   - default: returns the given default when a value is empty, ex: {{first .Tags | default "untagged"}}
+type NameTemplate struct {
+	tmpl *template.Template
+}
+
+var nameTemplateFuncs = template.FuncMap{
+	"slug":    slugify,
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"first":   first,
+	"default": defaultValue,
+}
+
+// ParseNameTemplate parses a file name template
+func ParseNameTemplate(text string) (*NameTemplate, error) {
+	tmpl, err := template.New("name").Funcs(nameTemplateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidNameTemplate, err)
+	}
+
+	return &NameTemplate{tmpl: tmpl}, nil
+}
+
+// Execute returns the name of the files of a drop, sanitized to be valid on any platform.
+// Separators left at the edges by empty values (ex: an untitled drop) are trimmed before sanitizing,
+// so that the suffix added to Windows reserved names is kept.
+func (t *NameTemplate) Execute(drop raindrop.Drop) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, drop); err != nil {
+		return "", fmt.Errorf("failed to generate file name: %w", err)
+	}
+
+	name := filename.Sanitize(strings.Trim(buf.String(), nameSeparators))
+	if strings.Trim(name, nameSeparators) == "" {
+		return "", fmt.Errorf("failed to generate file name: template returned an empty name for drop %d", drop.ID)
+	}
+
+	return name, nil
+}
+
+// slugify converts a string into a lowercase name made of letters, digits and dashes
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+
+		if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// first returns the first value of a list, or an empty string if the list is empty.
+// Unlike index, it does not fail on drops without tags.
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// defaultValue returns def when the value is empty
+func defaultValue(def, value string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,159 @@
+package downloader_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/raindrop-images-dl/internal/downloader"
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+func TestNameTemplate(t *testing.T) {
+	t.Parallel()
+
+	drop := raindrop.Drop{
+		ID:      859330230,
+		Title:   "Funny Cat: the Sequel!",
+		Tags:    []string{"Reaction", "cats"},
+		Created: time.Date(2024, 9, 22, 21, 37, 3, 0, time.UTC),
+		Domain:  "instagram.com",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		drop     raindrop.Drop
+		expected string
+	}{
+		{
+			name:     "Default",
+			template: downloader.DefaultNameTemplate,
+			drop:     drop,
+			expected: "859330230-funny-cat-the-sequel",
+		},
+		{
+			name:     "DefaultWithoutTitle",
+			template: downloader.DefaultNameTemplate,
+			drop:     raindrop.Drop{ID: 1},
+			expected: "1",
+		},
+		{
+			name:     "DateTagAndDomain",
+			template: `{{.Created.Format "2006-01-02"}}_{{first .Tags | lower}}_{{.Domain}}`,
+			drop:     drop,
+			expected: "2024-09-22_reaction_instagram.com",
+		},
+		{
+			name:     "FirstTagWithoutTags",
+			template: `{{.ID}}-{{first .Tags}}`,
+			drop:     raindrop.Drop{ID: 1},
+			expected: "1",
+		},
+		{
+			name:     "DefaultTag",
+			template: `{{first .Tags | default "untagged"}}-{{.ID}}`,
+			drop:     drop,
+			expected: "Reaction-859330230",
+		},
+		{
+			name:     "DefaultTagWithoutTags",
+			template: `{{first .Tags | default "untagged"}}-{{.ID}}`,
+			drop:     raindrop.Drop{ID: 1},
+			expected: "untagged-1",
+		},
+		{
+			name:     "WindowsReservedName",
+			template: "{{.Title}}",
+			drop:     raindrop.Drop{ID: 1, Title: "con"},
+			expected: "con_",
+		},
+		{
+			name:     "WindowsReservedNameUppercase",
+			template: "{{.Title}}",
+			drop:     raindrop.Drop{ID: 1, Title: "CON"},
+			expected: "CON_",
+		},
+		{
+			name:     "WindowsReservedNameWithEmptyValue",
+			template: "{{.Title}}-{{.Domain}}",
+			drop:     raindrop.Drop{ID: 1, Title: "nul"},
+			expected: "nul_",
+		},
+		{
+			name:     "WindowsReservedNameWithTrailingDot",
+			template: "{{.Title}}",
+			drop:     raindrop.Drop{ID: 1, Title: "aux."},
+			expected: "aux_",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tmpl, err := downloader.ParseNameTemplate(tt.template)
+			require.NoError(t, err)
+
+			name, err := tmpl.Execute(tt.drop)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, name)
+		})
+	}
+
+	t.Run("WithInvalidTemplate_ReturnsError", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := downloader.ParseNameTemplate("{{.Title")
+		assert.ErrorIs(t, err, downloader.ErrInvalidNameTemplate)
+	})
+
+	t.Run("WithUnknownField_ReturnsError", func(t *testing.T) {
+		t.Parallel()
+
+		tmpl, err := downloader.ParseNameTemplate("{{.Unknown}}")
+		require.NoError(t, err)
+
+		_, err = tmpl.Execute(drop)
+		assert.Error(t, err)
+	})
+
+	t.Run("WithEmptyResult_ReturnsError", func(t *testing.T) {
+		t.Parallel()
+
+		for _, text := range []string{
+			"{{.Title | slug}}",
+			`{{.Title | default ""}}`,
+			"{{first .Tags}}",
+			"{{first .Tags}} -_. {{.Domain}}",
+		} {
+			tmpl, err := downloader.ParseNameTemplate(text)
+			require.NoError(t, err)
+
+			name, err := tmpl.Execute(raindrop.Drop{ID: 1})
+			assert.Error(t, err, "template %q returned %q", text, name)
+		}
+	})
+
+	t.Run("WithIndexOnUntaggedDrop_ReturnsError", func(t *testing.T) {
+		t.Parallel()
+
+		untagged := raindrop.Drop{ID: 1, Title: "Untagged"}
+
+		tmpl, err := downloader.ParseNameTemplate("{{.ID}}-{{index .Tags 0}}")
+		require.NoError(t, err)
+
+		_, err = tmpl.Execute(untagged)
+		assert.ErrorContains(t, err, "index out of range")
+
+		// The same name is generated with first and default, which accept drops without tags
+		tmpl, err = downloader.ParseNameTemplate(`{{.ID}}-{{first .Tags | default "untagged"}}`)
+		require.NoError(t, err)
+
+		name, err := tmpl.Execute(untagged)
+		require.NoError(t, err)
+		assert.Equal(t, "1-untagged", name)
+	})
+}
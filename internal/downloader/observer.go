@@ -0,0 +1,76 @@
+package downloader
+
+import (
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+// Observer is notified of the progress of a download run, ex: to index the saved files.
+// The downloader calls the observers one event at a time, even when the drops are downloaded in parallel,
+// so implementations do not need to be safe for concurrent use. They should return quickly, as the download waits for them.
+// In dry-run mode, the drops that would be downloaded are reported as downloaded, with the path and size they would have.
+type Observer interface {
+	// OnCollectionStart is called before the drops of a collection are downloaded into its directory, relative to the output directory
+	OnCollectionStart(collection raindrop.CollectionItem, path string)
+	// OnPageFetched is called for each page of drops of the given type listed from a collection.
+	// The count is the total number of drops of that type in the collection, matching the search.
+	OnPageFetched(collection raindrop.CollectionItem, dropType raindrop.DropType, page int, count int)
+	// OnItemSkipped is called for drops that were already up-to-date or had nothing to download.
+	// The path is empty when the drop has no file.
+	OnItemSkipped(drop raindrop.Drop, path string)
+	// OnItemDownloaded is called when the files of a drop were saved, with the path of its main file and the size of its files
+	OnItemDownloaded(path string, drop raindrop.Drop, bytes int64)
+	// OnItemFailed is called when a drop could not be downloaded
+	OnItemFailed(drop raindrop.Drop, err error)
+	// OnCollectionComplete is called once the drops of a collection were processed, with the error that aborted it, if any.
+	// The drops that failed are reported by OnItemFailed. The error is the context error when the run was interrupted.
+	OnCollectionComplete(collection raindrop.CollectionItem, err error)
+	// OnComplete is called with the result of the run, once all the collections were processed
+	OnComplete(result Result)
+}
+
+// NopObserver implements Observer with methods that do nothing.
+// It can be embedded to only implement some of the callbacks.
+type NopObserver struct{}
+
+func (NopObserver) OnCollectionStart(raindrop.CollectionItem, string)                  {}
+func (NopObserver) OnPageFetched(raindrop.CollectionItem, raindrop.DropType, int, int) {}
+func (NopObserver) OnItemSkipped(raindrop.Drop, string)                                {}
+func (NopObserver) OnItemDownloaded(string, raindrop.Drop, int64)                      {}
+func (NopObserver) OnItemFailed(raindrop.Drop, error)                                  {}
+func (NopObserver) OnCollectionComplete(raindrop.CollectionItem, error)                {}
+func (NopObserver) OnComplete(Result)                                                  {}
+
+// WithObserver is a functional option to register an observer of the download runs. It can be used several times.
+func WithObserver(observer Observer) Option {
+	return func(d *Downloader) {
+		d.observers = append(d.observers, observer)
+	}
+}
+
+// notify calls fn with each observer, holding the lock so that events are delivered one at a time
+func (d *Downloader) notify(fn func(Observer)) {
+	if len(d.observers) == 0 {
+		return
+	}
+
+	d.observersMu.Lock()
+	defer d.observersMu.Unlock()
+
+	for _, observer := range d.observers {
+		fn(observer)
+	}
+}
+
+// notifyItem notifies the observers of the outcome of a drop
+func (d *Downloader) notifyItem(item raindrop.Drop, outcome itemOutcome, err error) {
+	d.notify(func(o Observer) {
+		switch {
+		case err != nil:
+			o.OnItemFailed(item, err)
+		case outcome.Skipped:
+			o.OnItemSkipped(item, outcome.Path)
+		default:
+			o.OnItemDownloaded(outcome.Path, item, outcome.Bytes)
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package downloader_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/raindrop-images-dl/internal/downloader"
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+// recordingObserver records the events it receives. It is not safe for concurrent use,
+// so the race detector reports observers called concurrently.
+type recordingObserver struct {
+	events []string
+	result downloader.Result
+}
+
+func (o *recordingObserver) OnCollectionStart(collection raindrop.CollectionItem, path string) {
+	o.events = append(o.events, fmt.Sprintf("collection %s %s", collection.Title, path))
+}
+
+func (o *recordingObserver) OnPageFetched(collection raindrop.CollectionItem, dropType raindrop.DropType, page int, count int) {
+	o.events = append(o.events, fmt.Sprintf("page %s %s %d %d", collection.Title, dropType, page, count))
+}
+
+func (o *recordingObserver) OnItemSkipped(drop raindrop.Drop, path string) {
+	o.events = append(o.events, fmt.Sprintf("skipped %d %q", drop.ID, path))
+}
+
+func (o *recordingObserver) OnItemDownloaded(path string, drop raindrop.Drop, bytes int64) {
+	o.events = append(o.events, fmt.Sprintf("downloaded %d %s %d", drop.ID, filepath.Base(path), bytes))
+}
+
+func (o *recordingObserver) OnItemFailed(drop raindrop.Drop, err error) {
+	o.events = append(o.events, fmt.Sprintf("failed %d", drop.ID))
+}
+
+func (o *recordingObserver) OnCollectionComplete(collection raindrop.CollectionItem, err error) {
+	o.events = append(o.events, fmt.Sprintf("collection complete %s %v", collection.Title, err))
+}
+
+func (o *recordingObserver) OnComplete(result downloader.Result) {
+	o.events = append(o.events, "complete")
+	o.result = result
+}
+
+func TestDownloader_Observer(t *testing.T) {
+	t.Parallel()
+
+	imageServer := setupImageServer(t)
+	rdClient := &MockRaindropClient{}
+	collectionID := 123
+	observer := &recordingObserver{}
+
+	dl, err := downloader.NewDownloader(
+		downloader.WithRaindropClient(rdClient),
+		downloader.WithConcurrency(4),
+		downloader.WithObserver(observer),
+		downloader.WithObserver(downloader.NopObserver{}),
+	)
+	require.NoError(t, err)
+
+	rdClient.On("GetCollectionByID", mock.Anything, collectionID).Return(&raindrop.CollectionItem{
+		ID:    int64(collectionID),
+		Title: "Memes",
+	}, nil)
+	rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(&raindrop.DropsPage{
+		Items: []raindrop.Drop{
+			{ID: 1, Title: "Kept", Cover: imageServer.URL + "/kept.png"},
+			{ID: 2, Title: "Gone", Cover: imageServer.URL + "/missing.png"},
+			{ID: 3, Title: "Empty"},
+		},
+		Count: 3,
+	}, nil)
+
+	_, err = dl.DownloadCollection(context.Background(), collectionID, t.TempDir(), false)
+	require.ErrorIs(t, err, downloader.ErrPartialFailure)
+
+	require.Len(t, observer.events, 7)
+	assert.Equal(t, []string{"collection Memes Memes", "page Memes image 0 3"}, observer.events[:2])
+	assert.ElementsMatch(t, []string{
+		fmt.Sprintf("downloaded 1 1-kept.png %d", len(pngBytes)),
+		"failed 2",
+		`skipped 3 ""`,
+	}, observer.events[2:5])
+	// A failed drop does not abort the collection
+	assert.Equal(t, []string{"collection complete Memes <nil>", "complete"}, observer.events[5:])
+	assert.Equal(t, 1, observer.result.Failed)
+}
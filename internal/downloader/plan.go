@@ -0,0 +1,200 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+// PlanAction is what a download would do with a file, reported by dry runs
+type PlanAction string
+
+const (
+	// PlanCreate means the file would be downloaded
+	PlanCreate PlanAction = "create"
+	// PlanSkip means the file is up-to-date, or already exists and would be kept
+	PlanSkip PlanAction = "skip"
+	// PlanOverwrite means the drop changed, and its file would be downloaded again
+	PlanOverwrite PlanAction = "overwrite"
+	// PlanPrune means the file would be removed, because its drop was deleted, changed or no longer matches the filters
+	PlanPrune PlanAction = "prune"
+)
+
+// PlannedFile is a file that a dry run would create, skip, overwrite or prune
+type PlannedFile struct {
+	Action     PlanAction `json:"action"`
+	Path       string     `json:"path"`
+	DropID     int64      `json:"drop_id,omitempty"`
+	Collection string     `json:"collection,omitempty"`
+	// Bytes is the size reported by the server for the files to download, or -1 when it is unknown
+	Bytes int64 `json:"bytes"`
+}
+
+// probedFile describes a remote file, as it would be saved by downloadFile
+type probedFile struct {
+	URL  string
+	Path string
+	// Size is the Content-Length of the file, or -1 when it is unknown
+	Size   int64
+	Exists bool
+}
+
+// probeFile resolves the path a file would be saved to, with a HEAD request instead of downloading it.
+// Without the first bytes of the file, its type is detected from the Content-Type header and the URL extension.
+func probeFile(ctx context.Context, mediaTypes MediaTypes, url, dest string) (*probedFile, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req) // #nosec
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	file := &probedFile{URL: url, Size: -1}
+	contentType := ""
+
+	switch {
+	case resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented:
+		// Some servers only support GET, so the type can only be guessed from the URL
+		slog.Debug("HEAD request not supported, guessing the file type from the URL", "url", url)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, fmt.Errorf("failed to download file: %s", resp.Status)
+	default:
+		contentType = resp.Header.Get("Content-Type")
+		if size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil {
+			file.Size = size
+		}
+	}
+
+	extension, err := mediaTypes.extension(contentType, nil, url)
+	if err != nil {
+		return nil, err
+	}
+
+	file.Path = dest + extension
+	file.Exists = fileExists(file.Path)
+
+	return file, nil
+}
+
+// probeFromSource resolves the main file of a drop, trying each candidate source in order like downloadFromSource
+func (d *Downloader) probeFromSource(ctx context.Context, item raindrop.Drop, dest string) (*probedFile, error) {
+	var errs []error
+	noURL := true
+
+	for _, source := range d.sources(item) {
+		url, err := d.sourceURL(ctx, item, source)
+		if err == nil {
+			var file *probedFile
+			if file, err = probeFile(ctx, d.mediaTypes, url, dest); err == nil {
+				return file, nil
+			}
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", source, err))
+		noURL = noURL && errors.Is(err, errNoSourceURL)
+	}
+
+	if noURL {
+		return nil, errNoSourceURL
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// planItem reports what downloadItem would do with a drop, without writing anything
+func (d *Downloader) planItem(ctx context.Context, run *collectionRun, collection string, item raindrop.Drop) (itemOutcome, error) {
+	media := d.additionalMedia(item)
+
+	// The files of a changed drop are overwritten when the new files have the same path,
+	// and the other ones are removed once the new files are downloaded
+	stale := make(map[string]struct{})
+
+	if entry, ok := run.state.get(item.ID); ok {
+		if d.isUnchanged(run, entry, item, media) {
+			for _, path := range entry.files() {
+				run.result.recordPlan(PlannedFile{Action: PlanSkip, Path: path, DropID: item.ID, Collection: collection})
+			}
+			return itemOutcome{Skipped: true, Path: entry.Path}, nil
+		}
+
+		for _, path := range entry.files() {
+			stale[path] = struct{}{}
+		}
+	}
+
+	name, err := d.nameTmpl.Execute(item)
+	if err != nil {
+		return itemOutcome{}, err
+	}
+	name = run.claimName(name, item.ID)
+
+	baseFilePath := filepath.Join(run.outputDir, name)
+
+	file, err := d.probeFromSource(ctx, item, baseFilePath)
+	if errors.Is(err, errNoSourceURL) {
+		slog.Warn("Bookmark has no URL field", "title", item.Title)
+		return itemOutcome{Skipped: true}, nil
+	}
+	if err != nil {
+		return itemOutcome{}, fmt.Errorf("failed to download image: %w", err)
+	}
+
+	files := []*probedFile{file}
+	for i, link := range media {
+		file, err := probeFile(ctx, d.mediaTypes, link, fmt.Sprintf("%s_%02d", baseFilePath, i+1))
+		if err != nil {
+			return itemOutcome{}, fmt.Errorf("failed to download media: %w", err)
+		}
+		files = append(files, file)
+	}
+
+	outcome := itemOutcome{Skipped: true, Path: file.Path}
+	var planned []PlannedFile
+
+	for _, f := range files {
+		_, replaced := stale[f.Path]
+		delete(stale, f.Path)
+
+		action := PlanCreate
+		switch {
+		case replaced && f.Exists:
+			action = PlanOverwrite
+		case f.Exists:
+			// Files found on disk, but missing from the state, are kept as they are
+			action = PlanSkip
+		}
+
+		plannedFile := PlannedFile{Action: action, Path: f.Path, DropID: item.ID, Collection: collection, Bytes: f.Size}
+		if action == PlanSkip {
+			plannedFile.Bytes = 0
+		} else {
+			outcome.Skipped = false
+			outcome.Bytes += max(f.Size, 0)
+		}
+		planned = append(planned, plannedFile)
+	}
+
+	for path := range stale {
+		if fileExists(path) {
+			planned = append(planned, PlannedFile{Action: PlanPrune, Path: path, DropID: item.ID, Collection: collection})
+		}
+	}
+
+	run.result.recordPlan(planned...)
+
+	return outcome, nil
+}
@@ -0,0 +1,150 @@
+package downloader_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/raindrop-images-dl/internal/downloader"
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+// headServer serves PNG images like imageServer, recording the request methods.
+// Paths starting with /nohead reject HEAD requests, and the ones starting with /missing are not found.
+type headServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	methods []string
+}
+
+func setupHeadServer(t *testing.T) *headServer {
+	t.Helper()
+
+	s := &headServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.methods = append(s.methods, r.Method)
+		s.mu.Unlock()
+
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/missing"):
+			w.WriteHeader(http.StatusNotFound)
+		case strings.HasPrefix(r.URL.Path, "/nohead") && r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(pngBytes)
+		}
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *headServer) requestMethods() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.methods
+}
+
+func TestDownloader_DryRun(t *testing.T) {
+	t.Parallel()
+
+	t.Run("WithNewOutputDir_PlansWithoutWriting", func(t *testing.T) {
+		t.Parallel()
+
+		server := setupHeadServer(t)
+		parentDir := t.TempDir()
+		outputDir := filepath.Join(parentDir, "backup")
+		rdClient := &MockRaindropClient{}
+
+		dl, err := downloader.NewDownloader(downloader.WithRaindropClient(rdClient), downloader.WithDryRun(true))
+		require.NoError(t, err)
+
+		rdClient.On("GetCollectionByID", mock.Anything, 123).Return(&raindrop.CollectionItem{ID: 123, Title: "Memes"}, nil)
+		rdClient.On("GetDropsFromCollection", mock.Anything, 123, 0, mock.Anything).Return(&raindrop.DropsPage{
+			Items: []raindrop.Drop{
+				{ID: 1, Title: "Cat", Cover: server.URL + "/cat"},
+				{ID: 2, Title: "Dog", Cover: server.URL + "/nohead/dog.jpg"},
+				{ID: 3, Title: "Gone", Cover: server.URL + "/missing.png"},
+			},
+		}, nil)
+
+		result, err := dl.DownloadCollection(context.Background(), 123, outputDir, true)
+		require.ErrorIs(t, err, downloader.ErrPartialFailure)
+
+		assert.True(t, result.DryRun)
+		assert.Equal(t, 2, result.Downloaded)
+		assert.Equal(t, 1, result.Failed)
+		assert.Equal(t, int64(len(pngBytes)), result.Bytes)
+		assert.Equal(t, []downloader.PlannedFile{
+			{Action: downloader.PlanCreate, Path: filepath.Join(outputDir, "Memes", "1-cat.png"), DropID: 1, Collection: "Memes", Bytes: int64(len(pngBytes))},
+			{Action: downloader.PlanCreate, Path: filepath.Join(outputDir, "Memes", "2-dog.jpg"), DropID: 2, Collection: "Memes", Bytes: -1},
+		}, result.Plan)
+
+		entries, err := os.ReadDir(parentDir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+		assert.NotContains(t, server.requestMethods(), http.MethodGet)
+	})
+
+	t.Run("WithPreviousRun_PlansSkipsAndOverwrites", func(t *testing.T) {
+		t.Parallel()
+
+		server := setupHeadServer(t)
+		outputDir := t.TempDir()
+		rdClient := &MockRaindropClient{}
+
+		unchanged := raindrop.Drop{ID: 1, Title: "Cat", Cover: server.URL + "/cat.png", LastUpdate: "2024-01-01"}
+		changed := raindrop.Drop{ID: 2, Title: "Dog", Cover: server.URL + "/dog.png", LastUpdate: "2024-01-01"}
+
+		rdClient.On("GetCollectionByID", mock.Anything, 123).Return(&raindrop.CollectionItem{ID: 123, Title: "Memes"}, nil)
+		rdClient.On("GetDropsFromCollection", mock.Anything, 123, 0, mock.Anything).Return(&raindrop.DropsPage{
+			Items: []raindrop.Drop{unchanged, changed},
+		}, nil).Once()
+
+		dl, err := downloader.NewDownloader(downloader.WithRaindropClient(rdClient))
+		require.NoError(t, err)
+		mustDownloadCollection(t, dl, context.Background(), 123, outputDir, false)
+
+		// A file saved by hand, which is not recorded in the state
+		existingPath := filepath.Join(outputDir, "Memes", "3-bird.png")
+		require.NoError(t, os.WriteFile(existingPath, pngBytes, 0o600))
+
+		changed.LastUpdate = "2024-02-01"
+		rdClient.On("GetDropsFromCollection", mock.Anything, 123, 0, mock.Anything).Return(&raindrop.DropsPage{
+			Items: []raindrop.Drop{unchanged, changed, {ID: 3, Title: "Bird", Cover: server.URL + "/bird.png"}},
+		}, nil).Once()
+
+		dl, err = downloader.NewDownloader(downloader.WithRaindropClient(rdClient), downloader.WithDryRun(true))
+		require.NoError(t, err)
+
+		statePath := filepath.Join(outputDir, downloader.StateFileName)
+		stateBefore, err := os.ReadFile(statePath)
+		require.NoError(t, err)
+
+		result := mustDownloadCollection(t, dl, context.Background(), 123, outputDir, false)
+
+		assert.Equal(t, 1, result.Downloaded)
+		assert.Equal(t, 2, result.Skipped)
+		assert.Equal(t, []downloader.PlannedFile{
+			{Action: downloader.PlanSkip, Path: filepath.Join(outputDir, "Memes", "1-cat.png"), DropID: 1, Collection: "Memes"},
+			{Action: downloader.PlanOverwrite, Path: filepath.Join(outputDir, "Memes", "2-dog.png"), DropID: 2, Collection: "Memes", Bytes: int64(len(pngBytes))},
+			{Action: downloader.PlanSkip, Path: existingPath, DropID: 3, Collection: "Memes"},
+		}, result.Plan)
+
+		stateAfter, err := os.ReadFile(statePath)
+		require.NoError(t, err)
+		assert.Equal(t, stateBefore, stateAfter)
+	})
+}
@@ -0,0 +1,152 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+// TrashDirName is the directory, at the root of the output directory, where pruned files are moved to
+const TrashDirName = ".trash"
+
+// PruneMode defines what happens to the local files of drops that no longer exist in the collection
+type PruneMode string
+
+const (
+	// PruneNone keeps the local files of deleted drops
+	PruneNone PruneMode = ""
+	// PruneTrash moves the local files of deleted drops to the trash directory
+	PruneTrash PruneMode = "trash"
+	// PruneDelete deletes the local files of deleted drops
+	PruneDelete PruneMode = "delete"
+)
+
+// ParsePruneMode converts a string into a PruneMode
+func ParsePruneMode(mode string) (PruneMode, error) {
+	switch PruneMode(mode) {
+	case PruneNone, PruneTrash, PruneDelete:
+		return PruneMode(mode), nil
+	}
+	return PruneNone, fmt.Errorf("%w: %q", ErrInvalidPruneMode, mode)
+}
+
+// prune removes the files of the drops recorded in the state for the collection directory that were deleted in Raindrop.
+// The drops that were not returned during this run may only be excluded by the search or the types, so they are
+// looked up in an unfiltered listing of the collection, and only pruned when they are missing from it.
+// Sub-directories belong to nested collections and are left alone.
+// It must only be called after a complete pass over the collection.
+func (d *Downloader) prune(ctx context.Context, run *collectionRun, collection raindrop.CollectionItem) error {
+	candidates := make(map[int64]stateEntry)
+	for id, entry := range run.state.entries() {
+		if _, ok := run.seen[id]; ok || filepath.Dir(entry.Path) != run.outputDir {
+			continue
+		}
+		candidates[id] = entry
+	}
+
+	// Skip the listing when every drop of the state was returned, which is the common case
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	existing, err := d.listDropIDs(ctx, collection)
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list the drops of collection %q to prune: %w", collection.Title, err)
+	}
+
+	for id, entry := range candidates {
+		if _, ok := existing[id]; ok {
+			continue
+		}
+
+		for _, path := range append(entry.files(), trimExt(entry.Path)+".info.json") {
+			if !fileExists(path) {
+				continue
+			}
+
+			if d.dryRun {
+				slog.Info("Would prune file of deleted drop", "path", path, "mode", d.pruneMode)
+				run.result.recordPlan(PlannedFile{Action: PlanPrune, Path: path, DropID: id})
+				continue
+			}
+
+			if err := d.pruneFile(run.state.dir, path, id); err != nil {
+				return err
+			}
+			slog.Info("Pruned file of deleted drop", "path", path, "mode", d.pruneMode)
+		}
+
+		if !d.dryRun {
+			run.state.remove(id)
+		}
+	}
+
+	return nil
+}
+
+// listDropIDs returns the IDs of all the drops of the collection, whatever their type or the search
+func (d *Downloader) listDropIDs(ctx context.Context, collection raindrop.CollectionItem) (map[int64]struct{}, error) {
+	ids := make(map[int64]struct{})
+
+	it := raindrop.NewDropIterator(ctx, d.rdClient, int(collection.ID), raindrop.ListOptions{})
+	for it.Next() {
+		ids[it.Drop().ID] = struct{}{}
+	}
+
+	return ids, it.Err()
+}
+
+// pruneFile deletes the file or moves it to the trash directory, keeping its path relative to the output directory
+func (d *Downloader) pruneFile(outputDir, path string, id int64) error {
+	if d.pruneMode == PruneDelete {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", path, err)
+		}
+		return nil
+	}
+
+	relPath, err := filepath.Rel(outputDir, path)
+	if err != nil {
+		return fmt.Errorf("failed to move %s to trash: %w", path, err)
+	}
+
+	trashPath := filepath.Join(outputDir, TrashDirName, relPath)
+	if err := ensureDir(filepath.Dir(trashPath)); err != nil {
+		return err
+	}
+
+	if err := os.Rename(path, availableTrashPath(trashPath, id)); err != nil {
+		return fmt.Errorf("failed to move %s to trash: %w", path, err)
+	}
+
+	return nil
+}
+
+// availableTrashPath returns a path of the trash that is not used yet, so that a file pruned earlier
+// with the same name, ex: by another drop that had the same name, is not overwritten.
+// The drop ID is added to the name when the path is taken, followed by a counter if needed.
+func availableTrashPath(trashPath string, id int64) string {
+	if !fileExists(trashPath) {
+		return trashPath
+	}
+
+	ext := filepath.Ext(trashPath)
+	if strings.HasSuffix(trashPath, ".info.json") {
+		ext = ".info.json"
+	}
+	base := strings.TrimSuffix(trashPath, ext)
+
+	candidate := fmt.Sprintf("%s_%d%s", base, id, ext)
+	for n := 2; fileExists(candidate); n++ {
+		candidate = fmt.Sprintf("%s_%d-%d%s", base, id, n, ext)
+	}
+	return candidate
+}
@@ -0,0 +1,202 @@
+package downloader_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/raindrop-images-dl/internal/downloader"
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+func TestParsePruneMode(t *testing.T) {
+	t.Parallel()
+
+	mode, err := downloader.ParsePruneMode("trash")
+	require.NoError(t, err)
+	assert.Equal(t, downloader.PruneTrash, mode)
+
+	_, err = downloader.ParsePruneMode("shred")
+	assert.ErrorIs(t, err, downloader.ErrInvalidPruneMode)
+}
+
+// setupPruneTest downloads two drops into a new output directory, and returns a mock client
+// whose next listings only contain the first drop, so the second one is considered deleted.
+func setupPruneTest(t *testing.T, opts ...downloader.Option) (dl *downloader.Downloader, rdClient *MockRaindropClient, outputDir string, deleted raindrop.Drop) {
+	t.Helper()
+
+	imageServer := setupImageServer(t)
+	outputDir = t.TempDir()
+	collectionID := 123
+
+	rdClient = &MockRaindropClient{}
+	dl, err := downloader.NewDownloader(append([]downloader.Option{downloader.WithRaindropClient(rdClient)}, opts...)...)
+	require.NoError(t, err)
+
+	rdClient.On("GetCollectionByID", mock.Anything, collectionID).Return(&raindrop.CollectionItem{
+		ID:    int64(collectionID),
+		Title: "Memes",
+	}, nil)
+
+	kept := raindrop.Drop{ID: 1, Title: "Kept", Cover: imageServer.URL + "/kept.png"}
+	deleted = raindrop.Drop{ID: 2, Title: "Deleted", Cover: imageServer.URL + "/deleted.png"}
+
+	rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(&raindrop.DropsPage{
+		Items: []raindrop.Drop{kept, deleted},
+	}, nil).Once()
+
+	mustDownloadCollection(t, dl, context.Background(), collectionID, outputDir, true)
+
+	// The drops are listed with the filters of the run, then without filters to find the deleted ones
+	rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(&raindrop.DropsPage{
+		Items: []raindrop.Drop{kept},
+	}, nil).Twice()
+
+	return dl, rdClient, outputDir, deleted
+}
+
+// expectFilteredListing replaces the next listings of the prune test, returning only the first drop
+// when the drops are filtered, while the second one still exists in the collection
+func expectFilteredListing(rdClient *MockRaindropClient, deleted raindrop.Drop) {
+	kept := raindrop.Drop{ID: 1, Title: "Kept"}
+	filtered := mock.MatchedBy(func(opts raindrop.ListOptions) bool { return !opts.Search.IsZero() })
+	unfiltered := mock.MatchedBy(func(opts raindrop.ListOptions) bool { return opts.Search.IsZero() })
+
+	rdClient.ExpectedCalls = rdClient.ExpectedCalls[:1]
+	rdClient.On("GetDropsFromCollection", mock.Anything, 123, 0, filtered).Return(&raindrop.DropsPage{
+		Items: []raindrop.Drop{kept},
+	}, nil)
+	rdClient.On("GetDropsFromCollection", mock.Anything, 123, 0, unfiltered).Return(&raindrop.DropsPage{
+		Items: []raindrop.Drop{kept, deleted},
+	}, nil).Once()
+}
+
+func TestDownloader_Prune(t *testing.T) {
+	t.Parallel()
+
+	t.Run("WithTrashMode_MovesFilesToTrash", func(t *testing.T) {
+		t.Parallel()
+
+		dl, _, outputDir, deleted := setupPruneTest(t, downloader.WithPruneMode(downloader.PruneTrash))
+
+		mustDownloadCollection(t, dl, context.Background(), 123, outputDir, true)
+
+		assert.NoFileExists(t, filepath.Join(outputDir, "Memes", defaultFileName(t, deleted)+".png"))
+		assert.NoFileExists(t, filepath.Join(outputDir, "Memes", defaultFileName(t, deleted)+".info.json"))
+		assert.FileExists(t, filepath.Join(outputDir, downloader.TrashDirName, "Memes", defaultFileName(t, deleted)+".png"))
+		assert.FileExists(t, filepath.Join(outputDir, downloader.TrashDirName, "Memes", defaultFileName(t, deleted)+".info.json"))
+		assert.FileExists(t, filepath.Join(outputDir, "Memes", "1-kept.png"))
+	})
+
+	t.Run("WithTrashMode_KeepsEarlierTrashedFiles", func(t *testing.T) {
+		t.Parallel()
+
+		dl, _, outputDir, deleted := setupPruneTest(t, downloader.WithPruneMode(downloader.PruneTrash))
+
+		// A file with the same name was pruned earlier, ex: from another drop that had the same name
+		trashDir := filepath.Join(outputDir, downloader.TrashDirName, "Memes")
+		require.NoError(t, os.MkdirAll(trashDir, 0o750))
+		earlier := filepath.Join(trashDir, defaultFileName(t, deleted)+".png")
+		require.NoError(t, os.WriteFile(earlier, []byte("earlier"), 0o600))
+
+		mustDownloadCollection(t, dl, context.Background(), 123, outputDir, true)
+
+		data, err := os.ReadFile(earlier)
+		require.NoError(t, err)
+		assert.Equal(t, "earlier", string(data))
+
+		assert.FileExists(t, filepath.Join(trashDir, defaultFileName(t, deleted)+"_2.png"))
+		assert.FileExists(t, filepath.Join(trashDir, defaultFileName(t, deleted)+".info.json"))
+	})
+
+	t.Run("WithDeleteMode_DeletesFiles", func(t *testing.T) {
+		t.Parallel()
+
+		dl, _, outputDir, deleted := setupPruneTest(t, downloader.WithPruneMode(downloader.PruneDelete))
+
+		mustDownloadCollection(t, dl, context.Background(), 123, outputDir, true)
+
+		assert.NoFileExists(t, filepath.Join(outputDir, "Memes", defaultFileName(t, deleted)+".png"))
+		assert.NoDirExists(t, filepath.Join(outputDir, downloader.TrashDirName))
+		assert.FileExists(t, filepath.Join(outputDir, "Memes", "1-kept.png"))
+	})
+
+	t.Run("WithDryRun_KeepsFiles", func(t *testing.T) {
+		t.Parallel()
+
+		_, rdClient, outputDir, deleted := setupPruneTest(t)
+
+		dl, err := downloader.NewDownloader(
+			downloader.WithRaindropClient(rdClient),
+			downloader.WithPruneMode(downloader.PruneDelete),
+			downloader.WithDryRun(true),
+		)
+		require.NoError(t, err)
+
+		result := mustDownloadCollection(t, dl, context.Background(), 123, outputDir, true)
+
+		deletedPath := filepath.Join(outputDir, "Memes", defaultFileName(t, deleted)+".png")
+		assert.FileExists(t, deletedPath)
+		assert.Contains(t, result.Plan, downloader.PlannedFile{Action: downloader.PlanPrune, Path: deletedPath, DropID: deleted.ID})
+	})
+
+	t.Run("WithPageError_DoesNotPrune", func(t *testing.T) {
+		t.Parallel()
+
+		dl, rdClient, outputDir, deleted := setupPruneTest(t, downloader.WithPruneMode(downloader.PruneDelete))
+
+		// Replace the next page response with an incomplete collection
+		rdClient.ExpectedCalls = rdClient.ExpectedCalls[:1]
+		rdClient.On("GetDropsFromCollection", mock.Anything, 123, 0, mock.Anything).Return(&raindrop.DropsPage{
+			HasMore: true,
+		}, nil).Once()
+		rdClient.On("GetDropsFromCollection", mock.Anything, 123, 1, mock.Anything).Return((*raindrop.DropsPage)(nil), errors.New("server error")).Once()
+
+		_, err := dl.DownloadCollection(context.Background(), 123, outputDir, true)
+		require.ErrorIs(t, err, downloader.ErrPartialFailure)
+
+		_, err = os.Stat(filepath.Join(outputDir, "Memes", defaultFileName(t, deleted)+".png"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("WithSearch_KeepsDropsOutsideTheFilter", func(t *testing.T) {
+		t.Parallel()
+
+		dl, rdClient, outputDir, deleted := setupPruneTest(t,
+			downloader.WithPruneMode(downloader.PruneDelete),
+			downloader.WithSearch(raindrop.NewSearch().Tags("x")),
+		)
+		expectFilteredListing(rdClient, deleted)
+
+		mustDownloadCollection(t, dl, context.Background(), 123, outputDir, true)
+
+		assert.FileExists(t, filepath.Join(outputDir, "Memes", defaultFileName(t, deleted)+".png"))
+		rdClient.AssertExpectations(t)
+	})
+
+	t.Run("WithOtherTypes_KeepsDropsOfTheFormerTypes", func(t *testing.T) {
+		t.Parallel()
+
+		// The first run downloads videos, the second one only images
+		_, rdClient, outputDir, deleted := setupPruneTest(t, downloader.WithTypes(raindrop.DropTypeVideo))
+		expectFilteredListing(rdClient, deleted)
+
+		dl, err := downloader.NewDownloader(
+			downloader.WithRaindropClient(rdClient),
+			downloader.WithPruneMode(downloader.PruneDelete),
+			downloader.WithTypes(raindrop.DropTypeImage),
+		)
+		require.NoError(t, err)
+
+		mustDownloadCollection(t, dl, context.Background(), 123, outputDir, true)
+
+		assert.FileExists(t, filepath.Join(outputDir, "Memes", defaultFileName(t, deleted)+".png"))
+		rdClient.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,124 @@
+package downloader
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+// Result summarizes a download run
+type Result struct {
+	// Downloaded is the number of drops whose files were saved
+	Downloaded int `json:"downloaded"`
+	// Skipped is the number of drops that were already up-to-date or had nothing to download
+	Skipped int `json:"skipped"`
+	// Failed is the number of drops that could not be downloaded
+	Failed int `json:"failed"`
+	// Bytes is the total size of the saved files
+	Bytes    int64         `json:"bytes"`
+	Duration time.Duration `json:"duration"`
+	Failures []Failure     `json:"failures,omitempty"`
+	// Errors are the errors that aborted a collection before all its drops were processed, like a page that could not be fetched
+	Errors []string `json:"errors,omitempty"`
+	// DryRun is true when nothing was written. The counts and bytes are then the ones the run would have downloaded.
+	DryRun bool `json:"dry_run,omitempty"`
+	// Plan lists the files a dry run would create, skip, overwrite or prune, sorted by path
+	Plan []PlannedFile `json:"plan,omitempty"`
+}
+
+// Failure describes a drop that could not be downloaded
+type Failure struct {
+	DropID     int64  `json:"drop_id"`
+	Title      string `json:"title"`
+	Collection string `json:"collection"`
+	Reason     string `json:"reason"`
+}
+
+// MarshalJSON encodes the result with a human-readable duration
+func (r Result) MarshalJSON() ([]byte, error) {
+	type result Result
+	return json.Marshal(struct {
+		result
+		Duration string `json:"duration"`
+	}{
+		result:   result(r),
+		Duration: r.Duration.String(),
+	})
+}
+
+// itemOutcome describes what happened to a drop that did not fail
+type itemOutcome struct {
+	// Skipped is true when nothing was downloaded
+	Skipped bool
+	// Path is the path of the main file of the drop
+	Path string
+	// Bytes is the size of the files downloaded for the drop
+	Bytes int64
+}
+
+// resultRecorder collects the outcome of each drop of a run. It is safe for concurrent use.
+type resultRecorder struct {
+	mu     sync.Mutex
+	start  time.Time
+	result Result
+}
+
+func newResultRecorder() *resultRecorder {
+	return &resultRecorder{start: time.Now()}
+}
+
+// recordItem records the outcome of a drop, or its failure if err is not nil
+func (r *resultRecorder) recordItem(collection string, item raindrop.Drop, outcome itemOutcome, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case err != nil:
+		r.result.Failed++
+		r.result.Failures = append(r.result.Failures, Failure{
+			DropID:     item.ID,
+			Title:      item.Title,
+			Collection: collection,
+			Reason:     err.Error(),
+		})
+	case outcome.Skipped:
+		r.result.Skipped++
+	default:
+		r.result.Downloaded++
+	}
+
+	r.result.Bytes += outcome.Bytes
+}
+
+// recordError records an error that aborted a collection
+func (r *resultRecorder) recordError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.result.Errors = append(r.result.Errors, err.Error())
+}
+
+// recordPlan records the files a dry run would change
+func (r *resultRecorder) recordPlan(files ...PlannedFile) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.result.Plan = append(r.result.Plan, files...)
+}
+
+// finish returns the result, with the duration of the run
+func (r *resultRecorder) finish() *Result {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := r.result
+	result.Duration = time.Since(r.start)
+	slices.SortStableFunc(result.Plan, func(a, b PlannedFile) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return &result
+}
@@ -0,0 +1,97 @@
+package downloader_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/raindrop-images-dl/internal/downloader"
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+func TestDownloader_Result(t *testing.T) {
+	t.Parallel()
+
+	t.Run("WithFailedItems_ReturnsPartialFailure", func(t *testing.T) {
+		t.Parallel()
+
+		dl, rdClient := setupTestDownloader(t)
+		imageServer := setupImageServer(t)
+		collectionID := 123
+
+		rdClient.On("GetCollectionByID", mock.Anything, collectionID).Return(&raindrop.CollectionItem{
+			ID:    int64(collectionID),
+			Title: "Memes",
+		}, nil)
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(&raindrop.DropsPage{
+			Items: []raindrop.Drop{
+				{ID: 1, Title: "Kept", Cover: imageServer.URL + "/kept.png"},
+				{ID: 2, Title: "Gone", Cover: imageServer.URL + "/missing.png"},
+				{ID: 3, Title: "Empty"},
+			},
+		}, nil)
+
+		result, err := dl.DownloadCollection(context.Background(), collectionID, t.TempDir(), false)
+		require.ErrorIs(t, err, downloader.ErrPartialFailure)
+		assert.ErrorContains(t, err, "1 of 3 drops failed")
+
+		assert.Equal(t, 1, result.Downloaded)
+		assert.Equal(t, 1, result.Skipped)
+		assert.Equal(t, 1, result.Failed)
+		assert.Equal(t, int64(len(pngBytes)), result.Bytes)
+		assert.Positive(t, result.Duration)
+
+		require.Len(t, result.Failures, 1)
+		assert.Equal(t, int64(2), result.Failures[0].DropID)
+		assert.Equal(t, "Gone", result.Failures[0].Title)
+		assert.Equal(t, "Memes", result.Failures[0].Collection)
+		assert.Contains(t, result.Failures[0].Reason, "404")
+	})
+
+	t.Run("WithPageError_RecordsTheError", func(t *testing.T) {
+		t.Parallel()
+
+		dl, rdClient := setupTestDownloader(t)
+		collectionID := 123
+
+		rdClient.On("GetCollectionByID", mock.Anything, collectionID).Return(&raindrop.CollectionItem{
+			ID:    int64(collectionID),
+			Title: "Memes",
+		}, nil)
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return((*raindrop.DropsPage)(nil), raindrop.ErrRateLimited)
+
+		result, err := dl.DownloadCollection(context.Background(), collectionID, t.TempDir(), false)
+		require.ErrorIs(t, err, downloader.ErrPartialFailure)
+		assert.ErrorIs(t, err, raindrop.ErrRateLimited)
+
+		require.Len(t, result.Errors, 1)
+		assert.Contains(t, result.Errors[0], "Memes")
+	})
+}
+
+func TestResult_MarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	data, err := json.Marshal(downloader.Result{
+		Downloaded: 2,
+		Failed:     1,
+		Bytes:      2048,
+		Duration:   1500 * time.Millisecond,
+		Failures:   []downloader.Failure{{DropID: 3, Title: "Gone", Collection: "Memes", Reason: "not found"}},
+	})
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"downloaded": 2,
+		"skipped": 0,
+		"failed": 1,
+		"bytes": 2048,
+		"duration": "1.5s",
+		"failures": [{"drop_id": 3, "title": "Gone", "collection": "Memes", "reason": "not found"}]
+	}`, string(data))
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log/slog"
 	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
 )
@@ -15,16 +17,42 @@ var (
 	ErrCollectionIDNotSet   = errors.New("collection ID not set")
 	ErrOutputDirNotSet      = errors.New("output directory not set")
 	ErrOutputDirNotExists   = errors.New("output directory does not exist")
+	ErrInvalidConcurrency   = errors.New("concurrency must be greater than zero")
+	ErrInvalidPruneMode     = errors.New("invalid prune mode")
+	ErrInvalidSource        = errors.New("invalid source")
+	ErrInvalidNameTemplate  = errors.New("invalid name template")
+	ErrInvalidType          = errors.New("invalid drop type")
+	ErrPartialFailure       = errors.New("some drops could not be downloaded")
 )
 
+// DefaultConcurrency is the number of items downloaded in parallel when no concurrency is configured
+const DefaultConcurrency = 4
+
 type RaindropClient interface {
 	GetCollectionByID(ctx context.Context, collectionID int) (*raindrop.CollectionItem, error)
-	GetImagesDropsFromCollection(ctx context.Context, collectionID int, page int) (*raindrop.ImageDrops, error)
+	GetRootCollections(ctx context.Context) ([]raindrop.CollectionItem, error)
+	GetChildCollections(ctx context.Context) ([]raindrop.CollectionItem, error)
+	GetDropsFromCollection(ctx context.Context, collectionID int, page int, opts raindrop.ListOptions) (*raindrop.DropsPage, error)
+	GetPermanentCopyURL(ctx context.Context, dropID int64) (string, error)
 }
 
 // Downloader is a client for the Raindrop API
 type Downloader struct {
-	rdClient RaindropClient
+	rdClient    RaindropClient
+	concurrency int
+	pruneMode   PruneMode
+	dryRun      bool
+	allMedia    bool
+	source      Source
+	nameTmpl    *NameTemplate
+	mediaTypes  MediaTypes
+	recursive   bool
+	filter      CollectionFilter
+	search      raindrop.Search
+	types       []raindrop.DropType
+	observers   []Observer
+	// observersMu serializes the calls to the observers
+	observersMu sync.Mutex
 }
 
 // Validate validates the Downloader configuration
@@ -32,6 +60,32 @@ func (d *Downloader) Validate() error {
 	if d.rdClient == nil {
 		return ErrRaindropClientNotSet
 	}
+
+	if d.nameTmpl == nil {
+		return ErrInvalidNameTemplate
+	}
+
+	if d.concurrency < 1 {
+		return ErrInvalidConcurrency
+	}
+
+	if _, err := ParsePruneMode(string(d.pruneMode)); err != nil {
+		return err
+	}
+
+	if _, err := ParseSource(string(d.source)); err != nil {
+		return err
+	}
+
+	if len(d.types) == 0 {
+		return fmt.Errorf("%w: no type selected", ErrInvalidType)
+	}
+
+	for _, dropType := range d.types {
+		if _, ok := supportedTypes[dropType]; !ok {
+			return fmt.Errorf("%w: %q", ErrInvalidType, dropType)
+		}
+	}
 	return nil
 }
 
@@ -45,9 +99,98 @@ func WithRaindropClient(client RaindropClient) Option {
 	}
 }
 
+// WithConcurrency is a functional option to set the number of items downloaded in parallel
+func WithConcurrency(concurrency int) Option {
+	return func(d *Downloader) {
+		d.concurrency = concurrency
+	}
+}
+
+// WithPruneMode is a functional option to mirror the collection, pruning the local files of drops deleted in Raindrop
+func WithPruneMode(mode PruneMode) Option {
+	return func(d *Downloader) {
+		d.pruneMode = mode
+	}
+}
+
+// WithDryRun is a functional option to only plan the download, reporting the files that would be created, skipped,
+// overwritten or pruned in the Result, without writing anything. Files are probed with HEAD requests instead of being downloaded.
+func WithDryRun(dryRun bool) Option {
+	return func(d *Downloader) {
+		d.dryRun = dryRun
+	}
+}
+
+// WithAllMedia is a functional option to download all the media of a drop, not just its cover
+func WithAllMedia(allMedia bool) Option {
+	return func(d *Downloader) {
+		d.allMedia = allMedia
+	}
+}
+
+// WithSource is a functional option to set where the image of each drop is downloaded from
+func WithSource(source Source) Option {
+	return func(d *Downloader) {
+		d.source = source
+	}
+}
+
+// WithNameTemplate is a functional option to set the template used to name the saved files
+func WithNameTemplate(tmpl *NameTemplate) Option {
+	return func(d *Downloader) {
+		d.nameTmpl = tmpl
+	}
+}
+
+// WithRecursive is a functional option to also download the nested collections, mirroring the collection tree as directories
+func WithRecursive(recursive bool) Option {
+	return func(d *Downloader) {
+		d.recursive = recursive
+	}
+}
+
+// WithCollectionFilter is a functional option to only download the collections selected by the filter
+func WithCollectionFilter(filter CollectionFilter) Option {
+	return func(d *Downloader) {
+		d.filter = filter
+	}
+}
+
+// WithSearch is a functional option to only download the drops matching the search
+func WithSearch(search raindrop.Search) Option {
+	return func(d *Downloader) {
+		d.search = search
+	}
+}
+
+// WithTypes is a functional option to set the types of drops to download
+func WithTypes(types ...raindrop.DropType) Option {
+	return func(d *Downloader) {
+		d.types = types
+	}
+}
+
+// WithMediaType is a functional option to support an additional media type, saved with the given extension (ex: ".jxl")
+func WithMediaType(mediaType, extension string) Option {
+	return func(d *Downloader) {
+		d.mediaTypes[strings.ToLower(mediaType)] = extension
+	}
+}
+
 // NewDownloader creates a new Downloader instance, applying any provided options
 func NewDownloader(opts ...Option) (*Downloader, error) {
-	dl := &Downloader{}
+	defaultNameTmpl, err := ParseNameTemplate(DefaultNameTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	dl := &Downloader{
+		concurrency: DefaultConcurrency,
+		source:      SourceCover,
+		nameTmpl:    defaultNameTmpl,
+		mediaTypes:  defaultMediaTypes.clone(),
+		types:       DefaultTypes,
+	}
 
 	for _, opt := range opts {
 		opt(dl)
@@ -60,83 +203,405 @@ func NewDownloader(opts ...Option) (*Downloader, error) {
 	return dl, nil
 }
 
-// DownloadCollection downloads all images from a Raindrop collection
-func (d *Downloader) DownloadCollection(ctx context.Context, collectionID int, outputDir string, genInfoJSON bool) error {
+// DownloadCollection downloads all images from a Raindrop collection.
+// Pages are fetched sequentially by a single producer, while the items of each page are
+// downloaded by a bounded pool of workers, so the next page is requested while the previous one is still downloading.
+// In recursive mode, the nested collections are downloaded too, each into a sub-directory of its parent.
+// The result is returned once the collection was resolved, even if the download failed. When some drops failed,
+// or a collection could not be fully listed, the error wraps ErrPartialFailure.
+func (d *Downloader) DownloadCollection(ctx context.Context, collectionID int, outputDir string, genInfoJSON bool) (*Result, error) {
 	if collectionID == 0 {
-		return ErrCollectionIDNotSet
+		return nil, ErrCollectionIDNotSet
+	}
+
+	if err := d.checkOutputDir(outputDir); err != nil {
+		return nil, err
+	}
+
+	collection, err := d.rdClient.GetCollectionByID(ctx, collectionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection with id %d: %w", collectionID, err)
+	}
+
+	collections := d.buildCollectionTree([]raindrop.CollectionItem{*collection}, nil)
+	if d.recursive {
+		if collections, err = d.collectionTree(ctx, *collection); err != nil {
+			return nil, err
+		}
 	}
 
+	return d.downloadCollections(ctx, outputDir, collections, genInfoJSON)
+}
+
+// DownloadAllCollections downloads the images of every collection in the account, including the nested
+// and Unsorted collections, each into its own directory. Its result and errors are the same as DownloadCollection.
+func (d *Downloader) DownloadAllCollections(ctx context.Context, outputDir string, genInfoJSON bool) (*Result, error) {
+	if err := d.checkOutputDir(outputDir); err != nil {
+		return nil, err
+	}
+
+	collections, err := d.accountTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.downloadCollections(ctx, outputDir, collections, genInfoJSON)
+}
+
+// checkOutputDir ensures the output directory is set and exists. Dry runs can plan the download into a new directory.
+func (d *Downloader) checkOutputDir(outputDir string) error {
 	if outputDir == "" {
 		return ErrOutputDirNotSet
 	}
 
-	// Ensure the output directory exists
-	if !dirExists(outputDir) {
+	if !d.dryRun && !dirExists(outputDir) {
 		return ErrOutputDirNotExists
 	}
 
-	collection, err := d.rdClient.GetCollectionByID(ctx, collectionID)
+	return nil
+}
+
+// downloadCollections downloads each collection into its directory, sharing the state of the output directory
+func (d *Downloader) downloadCollections(ctx context.Context, outputDir string, collections []collectionDir, genInfoJSON bool) (*Result, error) {
+	st, err := loadState(outputDir)
 	if err != nil {
-		return fmt.Errorf("failed to get collection with id %d: %w", collectionID, err)
+		return nil, err
 	}
 
-	slog.Info("Downloading collection", "name", collection.Title)
+	recorder := newResultRecorder()
+	recorder.result.DryRun = d.dryRun
 
-	page := 0
-	for {
-		slog.Info("Processing page", "page", page)
+	// A failed collection does not prevent the others from being downloaded
+	var runErrs []error
+	for _, c := range collections {
+		if ctx.Err() != nil {
+			break
+		}
 
-		items, err := d.rdClient.GetImagesDropsFromCollection(ctx, collectionID, page)
+		err := d.downloadCollectionDir(ctx, st, recorder, c, genInfoJSON)
 		if err != nil {
-			slog.Error("Failed to get images from collection", "collection", collection.Title, "page", page, "error", err)
-			break
+			recorder.recordError(err)
+			runErrs = append(runErrs, fmt.Errorf("%w: %w", ErrPartialFailure, err))
+		} else if ctx.Err() != nil {
+			err = ctx.Err()
 		}
+		d.notify(func(o Observer) { o.OnCollectionComplete(c.Collection, err) })
+	}
+
+	result := recorder.finish()
+	d.notify(func(o Observer) { o.OnComplete(*result) })
+
+	if result.Failed > 0 {
+		total := result.Downloaded + result.Skipped + result.Failed
+		runErrs = append(runErrs, fmt.Errorf("%w: %d of %d drops failed to download", ErrPartialFailure, result.Failed, total))
+	}
+	runErr := errors.Join(runErrs...)
 
-		// Download each item
-		for _, item := range items.Items {
-			slog.Info("Downloading item", "title", item.Title)
+	// Save the state even if the run was interrupted, so the items already downloaded are not fetched again
+	if !d.dryRun {
+		if err := st.save(); err != nil {
+			return result, errors.Join(runErr, err)
+		}
+	}
 
-			if err := d.downloadItem(ctx, item, collection.Title, outputDir, genInfoJSON); err != nil {
-				slog.Error("Failed to download item", "title", item.Title, "error", err)
+	if runErr != nil {
+		return result, runErr
+	}
+
+	return result, ctx.Err()
+}
+
+// downloadCollectionDir downloads the drops of a single collection into its directory, and prunes the deleted ones
+func (d *Downloader) downloadCollectionDir(ctx context.Context, st *state, recorder *resultRecorder, c collectionDir, genInfoJSON bool) error {
+	slog.Info("Downloading collection", "name", c.Collection.Title, "path", c.Path, "concurrency", d.concurrency)
+
+	// Ensure collection-specific directory exists, before any worker starts writing to it
+	itemOutputDir := filepath.Join(st.dir, c.Path)
+	if !d.dryRun {
+		if err := ensureDir(itemOutputDir); err != nil {
+			return fmt.Errorf("failed to create directory for collection: %w", err)
+		}
+	}
+
+	run := newCollectionRun(itemOutputDir, genInfoJSON, st, recorder)
+	d.notify(func(o Observer) { o.OnCollectionStart(c.Collection, c.Path) })
+
+	items := make(chan raindrop.Drop)
+
+	var wg sync.WaitGroup
+	for i := 0; i < d.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range items {
+				slog.Info("Downloading item", "title", item.Title)
+
+				outcome, err := d.processItem(ctx, run, c.Collection.Title, item)
+				if err != nil {
+					// Items interrupted by a cancellation are not failures, they are downloaded on the next run
+					if ctx.Err() != nil {
+						continue
+					}
+					slog.Error("Failed to download item", "title", item.Title, "error", err)
+				}
+				run.result.recordItem(c.Collection.Title, item, outcome, err)
+				d.notifyItem(item, outcome, err)
 			}
+		}()
+	}
+
+	err := d.fetchPages(ctx, run, c.Collection, items)
+	close(items)
+	wg.Wait()
+
+	if err != nil {
+		return fmt.Errorf("collection %q: %w", c.Collection.Title, err)
+	}
+
+	// Only prune after a complete pass, otherwise drops from the missing pages would be considered deleted
+	if ctx.Err() == nil && d.pruneMode != PruneNone {
+		return d.prune(ctx, run, c.Collection)
+	}
+
+	return nil
+}
+
+// collectionRun holds the settings and state of a single DownloadCollection call, shared by the workers
+type collectionRun struct {
+	// outputDir is the directory where the collection items are saved
+	outputDir   string
+	genInfoJSON bool
+	state       *state
+	result      *resultRecorder
+	// seen holds the IDs of the drops returned by Raindrop. It is only written by the page producer.
+	seen map[int64]struct{}
+
+	namesMu sync.Mutex
+	// names maps the lowercase file names in use in the output directory to the ID of their drop
+	names map[string]int64
+}
+
+func newCollectionRun(outputDir string, genInfoJSON bool, st *state, recorder *resultRecorder) *collectionRun {
+	run := &collectionRun{
+		outputDir:   outputDir,
+		genInfoJSON: genInfoJSON,
+		state:       st,
+		result:      recorder,
+		seen:        make(map[int64]struct{}),
+		names:       make(map[string]int64),
+	}
+
+	// Names recorded by previous runs stay reserved for their drops
+	for id, entry := range st.entries() {
+		if filepath.Dir(entry.Path) == outputDir {
+			run.names[strings.ToLower(filepath.Base(trimExt(entry.Path)))] = id
 		}
+	}
 
-		// Exit if no more items to process
-		if !items.HasMore {
-			break
+	return run
+}
+
+// claimName reserves a file name for a drop. If the name is already used by another drop,
+// the drop ID is appended to it, so that the second drop is not mistaken for an existing file.
+// Names are compared case-insensitively, as some file systems are.
+func (r *collectionRun) claimName(name string, dropID int64) string {
+	r.namesMu.Lock()
+	defer r.namesMu.Unlock()
+
+	if owner, ok := r.names[strings.ToLower(name)]; ok && owner != dropID {
+		name = fmt.Sprintf("%s-%d", name, dropID)
+	}
+
+	r.names[strings.ToLower(name)] = dropID
+	return name
+}
+
+// fetchPages retrieves every page of the collection, for each of the selected drop types, and sends its items to the workers.
+// It stops on the first page error, so that an incomplete backup is reported instead of silently truncated,
+// or when the context is cancelled.
+func (d *Downloader) fetchPages(ctx context.Context, run *collectionRun, collection raindrop.CollectionItem, items chan<- raindrop.Drop) error {
+	for _, dropType := range d.types {
+		if err := d.fetchTypePages(ctx, run, collection, dropType, items); err != nil {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return nil
 		}
-		page++
 	}
 
 	return nil
 }
 
-// downloadItem handles downloading an individual item
-func (d *Downloader) downloadItem(ctx context.Context, item raindrop.Drop, collectionName, outputDir string, genInfoJSON bool) error {
-	// Ensure collection-specific directory exists
-	itemOutputDir := filepath.Join(outputDir, collectionName)
-	if err := ensureDir(itemOutputDir); err != nil {
-		return fmt.Errorf("failed to create directory for collection: %w", err)
+// fetchTypePages retrieves every page of the drops of the given type
+func (d *Downloader) fetchTypePages(ctx context.Context, run *collectionRun, collection raindrop.CollectionItem, dropType raindrop.DropType, items chan<- raindrop.Drop) error {
+	it := raindrop.NewDropIterator(ctx, d.rdClient, int(collection.ID), raindrop.ListOptions{Search: d.search.Type(dropType)})
+
+	page := -1
+	for it.Next() {
+		if it.Page() != page {
+			page = it.Page()
+			slog.Info("Processing page", "type", dropType, "page", page)
+			d.notify(func(o Observer) { o.OnPageFetched(collection, dropType, page, it.Count()) })
+		}
+
+		item := it.Drop()
+		run.seen[item.ID] = struct{}{}
+
+		select {
+		case items <- item:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	if err := it.Err(); err != nil && ctx.Err() == nil {
+		slog.Error("Failed to get drops from collection", "collection", collection.Title, "type", dropType, "error", err)
+		return fmt.Errorf("failed to get %s drops: %w", dropType, err)
 	}
 
-	imageURL := item.GetFileLink()
-	if imageURL == "" {
+	return nil
+}
+
+// processItem downloads a drop, or only plans its download in dry-run mode
+func (d *Downloader) processItem(ctx context.Context, run *collectionRun, collection string, item raindrop.Drop) (itemOutcome, error) {
+	if d.dryRun {
+		return d.planItem(ctx, run, collection, item)
+	}
+	return d.downloadItem(ctx, run, item)
+}
+
+// downloadItem handles downloading an individual item.
+// Items recorded in the state with the same source and last update are not requested again.
+func (d *Downloader) downloadItem(ctx context.Context, run *collectionRun, item raindrop.Drop) (itemOutcome, error) {
+	media := d.additionalMedia(item)
+
+	// The files of a drop that changed or moved to another collection in Raindrop are replaced by the new ones.
+	// They are only removed once the new files are downloaded, so that a failed download does not lose the only copy.
+	stale, hasStale := run.state.get(item.ID)
+	if hasStale && d.isUnchanged(run, stale, item, media) {
+		slog.Info("Item unchanged since last run, skipping", "title", item.Title, "path", stale.Path)
+		return itemOutcome{Skipped: true, Path: stale.Path}, d.createItemInfoFile(run, trimExt(stale.Path), item, stale.files(), false)
+	}
+
+	var replaced fileSet
+	if hasStale {
+		replaced = newFileSet(stale.files()...)
+	}
+
+	name, err := d.nameTmpl.Execute(item)
+	if err != nil {
+		return itemOutcome{}, err
+	}
+	name = run.claimName(name, item.ID)
+
+	// Download the main file, followed by the additional media with an indexed name
+	baseFilePath := filepath.Join(run.outputDir, name)
+
+	file, err := d.downloadFromSource(ctx, item, baseFilePath, replaced)
+	if errors.Is(err, errNoSourceURL) {
 		slog.Warn("Bookmark has no URL field", "title", item.Title)
+		return itemOutcome{Skipped: true}, nil
+	}
+	if err != nil {
+		return itemOutcome{}, fmt.Errorf("failed to download image: %w", err)
+	}
+
+	// Files found on disk, but missing from the state, are kept as they are
+	outcome := itemOutcome{Path: file.Path, Skipped: file.Existed}
+	if !file.Existed {
+		outcome.Bytes += file.Size
+	}
+
+	entry := stateEntry{
+		Path:       file.Path,
+		Checksum:   file.Checksum,
+		LastUpdate: item.LastUpdate,
+		SourceURL:  file.URL,
+		Source:     d.stateSource(item),
+	}
+
+	for i, link := range media {
+		file, err := downloadFile(ctx, d.mediaTypes, link, fmt.Sprintf("%s_%02d", baseFilePath, i+1), replaced)
+		if err != nil {
+			return outcome, fmt.Errorf("failed to download media: %w", err)
+		}
+		entry.Assets = append(entry.Assets, file.Path)
+
+		if !file.Existed {
+			outcome.Skipped = false
+			outcome.Bytes += file.Size
+		}
+	}
+
+	if hasStale {
+		keep := entry.files()
+		if run.genInfoJSON {
+			keep = append(keep, baseFilePath+".info.json")
+		}
+		removeStaleFiles(stale, keep)
+	}
+
+	if err := run.state.set(item.ID, entry); err != nil {
+		return outcome, err
+	}
+
+	return outcome, d.createItemInfoFile(run, baseFilePath, item, entry.files(), true)
+}
+
+// additionalMedia returns the links of the media to download besides the main file of the drop
+func (d *Downloader) additionalMedia(item raindrop.Drop) []string {
+	if !d.allMedia {
 		return nil
 	}
 
-	// Download image
-	baseFilePath := filepath.Join(itemOutputDir, item.GetName())
-	if err := downloadFile(imageURL, baseFilePath); err != nil {
-		return fmt.Errorf("failed to download image: %w", err)
+	var media []string
+	for _, link := range item.GetMediaLinks() {
+		if link != item.Cover {
+			media = append(media, link)
+		}
+	}
+	return media
+}
+
+// stateSource returns the source recorded in the state, which is empty for the default cover source
+// and for the drop types that do not depend on the source setting
+func (d *Downloader) stateSource(item raindrop.Drop) string {
+	if d.source == SourceCover || !usesSourceSetting(item.Type) {
+		return ""
+	}
+	return string(d.source)
+}
+
+// isUnchanged reports whether the files recorded in the state for a drop are up-to-date and in the collection directory
+func (d *Downloader) isUnchanged(run *collectionRun, entry stateEntry, item raindrop.Drop, media []string) bool {
+	if entry.LastUpdate != item.LastUpdate || entry.Source != d.stateSource(item) || len(entry.Assets) != len(media) {
+		return false
+	}
+
+	if filepath.Dir(entry.Path) != run.outputDir {
+		return false
 	}
 
-	// Create info.json file
-	if genInfoJSON {
-		if err := createInfoFile(baseFilePath, item); err != nil {
-			return fmt.Errorf("failed to create info file: %w", err)
+	for _, path := range entry.files() {
+		if !fileExists(path) {
+			return false
 		}
 	}
 
+	return true
+}
+
+// createItemInfoFile creates the info.json file of an item, when enabled.
+// The file of a new or changed drop is rewritten, while the one of an unchanged drop is only created if missing.
+func (d *Downloader) createItemInfoFile(run *collectionRun, baseFilePath string, item raindrop.Drop, files []string, overwrite bool) error {
+	if !run.genInfoJSON {
+		return nil
+	}
+
+	if err := createInfoFile(baseFilePath, item, files, overwrite); err != nil {
+		return fmt.Errorf("failed to create info file: %w", err)
+	}
+
 	return nil
 }
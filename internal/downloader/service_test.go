@@ -4,8 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -26,9 +32,24 @@ func (m *MockRaindropClient) GetCollectionByID(ctx context.Context, collectionID
 	return args.Get(0).(*raindrop.CollectionItem), args.Error(1)
 }
 
-func (m *MockRaindropClient) GetImagesDropsFromCollection(ctx context.Context, collectionID int, page int) (*raindrop.ImageDrops, error) {
-	args := m.Called(ctx, collectionID, page)
-	return args.Get(0).(*raindrop.ImageDrops), args.Error(1)
+func (m *MockRaindropClient) GetRootCollections(ctx context.Context) ([]raindrop.CollectionItem, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]raindrop.CollectionItem), args.Error(1)
+}
+
+func (m *MockRaindropClient) GetChildCollections(ctx context.Context) ([]raindrop.CollectionItem, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]raindrop.CollectionItem), args.Error(1)
+}
+
+func (m *MockRaindropClient) GetDropsFromCollection(ctx context.Context, collectionID int, page int, opts raindrop.ListOptions) (*raindrop.DropsPage, error) {
+	args := m.Called(ctx, collectionID, page, opts)
+	return args.Get(0).(*raindrop.DropsPage), args.Error(1)
+}
+
+func (m *MockRaindropClient) GetPermanentCopyURL(ctx context.Context, dropID int64) (string, error) {
+	args := m.Called(ctx, dropID)
+	return args.String(0), args.Error(1)
 }
 
 func setupTestDownloader(t *testing.T) (*downloader.Downloader, *MockRaindropClient) {
@@ -44,6 +65,68 @@ func setupTestDownloader(t *testing.T) (*downloader.Downloader, *MockRaindropCli
 	return dl, rdClient
 }
 
+// pngBytes is the signature of a PNG file, enough to be served as a fake image
+var pngBytes = []byte{0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a}
+
+// imageServer is a local HTTP server that serves a PNG image on any path, except the ones starting with /missing
+type imageServer struct {
+	*httptest.Server
+	requests atomic.Int32
+}
+
+func setupImageServer(t *testing.T) *imageServer {
+	t.Helper()
+
+	s := &imageServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		if strings.HasPrefix(r.URL.Path, "/missing") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(pngBytes)
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// defaultFileName returns the name given to the files of a drop by the default name template
+func defaultFileName(t *testing.T, drop raindrop.Drop) string {
+	t.Helper()
+
+	tmpl, err := downloader.ParseNameTemplate(downloader.DefaultNameTemplate)
+	require.NoError(t, err)
+
+	name, err := tmpl.Execute(drop)
+	require.NoError(t, err)
+
+	return name
+}
+
+// mustDownloadCollection downloads the collection, failing the test on error
+func mustDownloadCollection(t *testing.T, dl *downloader.Downloader, ctx context.Context, collectionID int, outputDir string, genInfoJSON bool) *downloader.Result {
+	t.Helper()
+
+	result, err := dl.DownloadCollection(ctx, collectionID, outputDir, genInfoJSON)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+
+	return result
+}
+
+// mustDownloadAllCollections downloads all collections, failing the test on error
+func mustDownloadAllCollections(t *testing.T, dl *downloader.Downloader, ctx context.Context, outputDir string, genInfoJSON bool) *downloader.Result {
+	t.Helper()
+
+	result, err := dl.DownloadAllCollections(ctx, outputDir, genInfoJSON)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+
+	return result
+}
+
 func generateTmpDir(t *testing.T) string {
 	t.Helper()
 
@@ -73,6 +156,17 @@ func TestNewDownloader(t *testing.T) {
 		assert.NoError(t, err)
 		assert.NotNil(t, dl)
 	})
+
+	t.Run("WithInvalidConcurrency_ReturnsError", func(t *testing.T) {
+		t.Parallel()
+		dl, err := downloader.NewDownloader(
+			downloader.WithRaindropClient(client),
+			downloader.WithConcurrency(0),
+		)
+
+		assert.ErrorIs(t, err, downloader.ErrInvalidConcurrency)
+		assert.Nil(t, dl)
+	})
 }
 
 func TestDownloader_DownloadCollection(t *testing.T) {
@@ -83,7 +177,7 @@ func TestDownloader_DownloadCollection(t *testing.T) {
 
 		dl, _ := setupTestDownloader(t)
 
-		err := dl.DownloadCollection(context.Background(), 0, "output", false)
+		_, err := dl.DownloadCollection(context.Background(), 0, "output", false)
 		assert.ErrorIs(t, err, downloader.ErrCollectionIDNotSet)
 	})
 
@@ -92,7 +186,7 @@ func TestDownloader_DownloadCollection(t *testing.T) {
 
 		dl, _ := setupTestDownloader(t)
 
-		err := dl.DownloadCollection(context.Background(), 123, "", false)
+		_, err := dl.DownloadCollection(context.Background(), 123, "", false)
 		assert.ErrorIs(t, err, downloader.ErrOutputDirNotSet)
 	})
 
@@ -100,7 +194,7 @@ func TestDownloader_DownloadCollection(t *testing.T) {
 		t.Parallel()
 
 		dl, _ := setupTestDownloader(t)
-		err := dl.DownloadCollection(context.Background(), 123, "non-existent-dir", false)
+		_, err := dl.DownloadCollection(context.Background(), 123, "non-existent-dir", false)
 		assert.ErrorIs(t, err, downloader.ErrOutputDirNotExists)
 	})
 
@@ -108,6 +202,7 @@ func TestDownloader_DownloadCollection(t *testing.T) {
 		t.Parallel()
 
 		dl, rdClient := setupTestDownloader(t)
+		imageServer := setupImageServer(t)
 		// create tmp dir
 		outputDir := generateTmpDir(t)
 		defer os.RemoveAll(outputDir)
@@ -125,24 +220,24 @@ func TestDownloader_DownloadCollection(t *testing.T) {
 			Tags:    []string{"tag1", "tag2"},
 			Created: time.Now(),
 			Link:    "https://example.com/image1.jpg",
-			Cover:   "https://placehold.co/600x400/000000/FFFFFF/png",
+			Cover:   imageServer.URL + "/image1.png",
 		}
 
-		mockDrops := &raindrop.ImageDrops{
+		mockDrops := &raindrop.DropsPage{
 			Items: []raindrop.Drop{
 				mockDrop,
 			},
 		}
-		rdClient.On("GetImagesDropsFromCollection", mock.Anything, collectionID, 0).Return(mockDrops, nil)
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(mockDrops, nil)
 
-		err := dl.DownloadCollection(context.Background(), collectionID, outputDir, true)
+		_, err := dl.DownloadCollection(context.Background(), collectionID, outputDir, true)
 		require.NoError(t, err)
 
 		rdClient.AssertExpectations(t)
 
 		// Check if the file was downloaded
-		downloadedFilePath := filepath.Join(outputDir, mockDrop.GetName()+".png")
-		downloadedFileInfoPath := filepath.Join(outputDir, mockDrop.GetName()+".info.json")
+		downloadedFilePath := filepath.Join(outputDir, defaultFileName(t, mockDrop)+".png")
+		downloadedFileInfoPath := filepath.Join(outputDir, defaultFileName(t, mockDrop)+".info.json")
 
 		_, err = os.Stat(downloadedFilePath)
 		assert.NoError(t, err)
@@ -170,6 +265,7 @@ func TestDownloader_DownloadCollection(t *testing.T) {
 		t.Parallel()
 
 		dl, rdClient := setupTestDownloader(t)
+		imageServer := setupImageServer(t)
 		// create tmp dir
 		outputDir := generateTmpDir(t)
 		defer os.RemoveAll(outputDir)
@@ -187,24 +283,24 @@ func TestDownloader_DownloadCollection(t *testing.T) {
 			Tags:    []string{"tag1", "tag2"},
 			Created: time.Now(),
 			Link:    "https://example.com/image1.jpg",
-			Cover:   "https://placehold.co/600x400/000000/FFFFFF/png",
+			Cover:   imageServer.URL + "/image1.png",
 		}
 
-		mockDrops := &raindrop.ImageDrops{
+		mockDrops := &raindrop.DropsPage{
 			Items: []raindrop.Drop{
 				mockDrop,
 			},
 		}
-		rdClient.On("GetImagesDropsFromCollection", mock.Anything, collectionID, 0).Return(mockDrops, nil)
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(mockDrops, nil)
 
-		err := dl.DownloadCollection(context.Background(), collectionID, outputDir, false)
+		_, err := dl.DownloadCollection(context.Background(), collectionID, outputDir, false)
 		require.NoError(t, err)
 
 		rdClient.AssertExpectations(t)
 
 		// Check if the file was downloaded
-		downloadedFilePath := filepath.Join(outputDir, mockDrop.GetName()+".png")
-		downloadedFileInfoPath := filepath.Join(outputDir, mockDrop.GetName()+".info.json")
+		downloadedFilePath := filepath.Join(outputDir, defaultFileName(t, mockDrop)+".png")
+		downloadedFileInfoPath := filepath.Join(outputDir, defaultFileName(t, mockDrop)+".info.json")
 
 		_, err = os.Stat(downloadedFilePath)
 		assert.NoError(t, err)
@@ -212,4 +308,373 @@ func TestDownloader_DownloadCollection(t *testing.T) {
 		_, err = os.Stat(downloadedFileInfoPath)
 		assert.True(t, os.IsNotExist(err))
 	})
+
+	t.Run("Success With Multiple Pages", func(t *testing.T) {
+		t.Parallel()
+
+		rdClient := &MockRaindropClient{}
+		dl, err := downloader.NewDownloader(
+			downloader.WithRaindropClient(rdClient),
+			downloader.WithConcurrency(3),
+		)
+		require.NoError(t, err)
+
+		imageServer := setupImageServer(t)
+		outputDir := generateTmpDir(t)
+		defer os.RemoveAll(outputDir)
+
+		collectionID := 123
+
+		rdClient.On("GetCollectionByID", mock.Anything, collectionID).Return(&raindrop.CollectionItem{
+			ID:    int64(collectionID),
+			Title: "Memes",
+		}, nil)
+
+		var drops []raindrop.Drop
+		for i := 1; i <= 10; i++ {
+			drops = append(drops, raindrop.Drop{
+				ID:    int64(i),
+				Title: fmt.Sprintf("Image %d", i),
+				Cover: fmt.Sprintf("%s/image%d.png", imageServer.URL, i),
+			})
+		}
+
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(&raindrop.DropsPage{
+			Items:   drops[:5],
+			HasMore: true,
+		}, nil)
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 1, mock.Anything).Return(&raindrop.DropsPage{
+			Items: drops[5:],
+		}, nil)
+
+		_, err = dl.DownloadCollection(context.Background(), collectionID, outputDir, true)
+		require.NoError(t, err)
+
+		rdClient.AssertExpectations(t)
+
+		for _, drop := range drops {
+			_, err = os.Stat(filepath.Join(outputDir, "Memes", defaultFileName(t, drop)+".png"))
+			assert.NoError(t, err)
+
+			_, err = os.Stat(filepath.Join(outputDir, "Memes", defaultFileName(t, drop)+".info.json"))
+			assert.NoError(t, err)
+		}
+	})
+
+	t.Run("WithCancelledContext_ReturnsError", func(t *testing.T) {
+		t.Parallel()
+
+		dl, rdClient := setupTestDownloader(t)
+		outputDir := generateTmpDir(t)
+		defer os.RemoveAll(outputDir)
+
+		collectionID := 123
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		rdClient.On("GetCollectionByID", mock.Anything, collectionID).Return(&raindrop.CollectionItem{
+			ID: int64(collectionID),
+		}, nil)
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(&raindrop.DropsPage{
+			Items: []raindrop.Drop{{ID: 1, Title: "Image 1", Cover: "https://example.com/image1.png"}},
+		}, nil)
+
+		_, err := dl.DownloadCollection(ctx, collectionID, outputDir, false)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("WithPageError_ReturnsError", func(t *testing.T) {
+		t.Parallel()
+
+		dl, rdClient := setupTestDownloader(t)
+		outputDir := generateTmpDir(t)
+		defer os.RemoveAll(outputDir)
+
+		collectionID := 123
+
+		rdClient.On("GetCollectionByID", mock.Anything, collectionID).Return(&raindrop.CollectionItem{
+			ID: int64(collectionID),
+		}, nil)
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(&raindrop.DropsPage{
+			HasMore: true,
+		}, nil)
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 1, mock.Anything).Return((*raindrop.DropsPage)(nil), errors.New("rate limited"))
+
+		_, err := dl.DownloadCollection(context.Background(), collectionID, outputDir, false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to get page 1")
+	})
+
+	t.Run("WithSearch_FiltersDrops", func(t *testing.T) {
+		t.Parallel()
+
+		rdClient := &MockRaindropClient{}
+		search := raindrop.NewSearch().Tags("reaction")
+		dl, err := downloader.NewDownloader(
+			downloader.WithRaindropClient(rdClient),
+			downloader.WithSearch(search),
+		)
+		require.NoError(t, err)
+
+		collectionID := 123
+
+		rdClient.On("GetCollectionByID", mock.Anything, collectionID).Return(&raindrop.CollectionItem{
+			ID: int64(collectionID),
+		}, nil)
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, raindrop.ListOptions{Search: search.Type(raindrop.DropTypeImage)}).Return(&raindrop.DropsPage{}, nil)
+
+		mustDownloadCollection(t, dl, context.Background(), collectionID, t.TempDir(), false)
+		rdClient.AssertExpectations(t)
+	})
+
+	t.Run("WithState_SkipsUnchangedItems", func(t *testing.T) {
+		t.Parallel()
+
+		dl, rdClient := setupTestDownloader(t)
+		imageServer := setupImageServer(t)
+		outputDir := generateTmpDir(t)
+		defer os.RemoveAll(outputDir)
+
+		collectionID := 123
+
+		rdClient.On("GetCollectionByID", mock.Anything, collectionID).Return(&raindrop.CollectionItem{
+			ID:    int64(collectionID),
+			Title: "Memes",
+		}, nil)
+
+		unchangedDrop := raindrop.Drop{
+			ID:         1,
+			Title:      "Image 1",
+			Cover:      imageServer.URL + "/image1.png",
+			LastUpdate: "2024-09-22T21:37:53.067Z",
+		}
+		changedDrop := raindrop.Drop{
+			ID:         2,
+			Title:      "Image 2",
+			Cover:      imageServer.URL + "/image2.png",
+			LastUpdate: "2024-09-22T21:37:53.067Z",
+		}
+
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(&raindrop.DropsPage{
+			Items: []raindrop.Drop{unchangedDrop, changedDrop},
+		}, nil).Once()
+
+		result, err := dl.DownloadCollection(context.Background(), collectionID, outputDir, true)
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), imageServer.requests.Load())
+		assert.Equal(t, 2, result.Downloaded)
+		assert.Equal(t, int64(2*len(pngBytes)), result.Bytes)
+
+		_, err = os.Stat(filepath.Join(outputDir, downloader.StateFileName))
+		require.NoError(t, err)
+
+		// Second run: the first drop is unchanged and the second one was renamed
+		renamedDrop := changedDrop
+		renamedDrop.Title = "Image 2 renamed"
+		renamedDrop.LastUpdate = "2024-10-01T10:00:00.000Z"
+
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(&raindrop.DropsPage{
+			Items: []raindrop.Drop{unchangedDrop, renamedDrop},
+		}, nil).Once()
+
+		result, err = dl.DownloadCollection(context.Background(), collectionID, outputDir, true)
+		require.NoError(t, err)
+		assert.Equal(t, int32(3), imageServer.requests.Load())
+		assert.Equal(t, 1, result.Downloaded)
+		assert.Equal(t, 1, result.Skipped)
+		assert.Equal(t, int64(len(pngBytes)), result.Bytes)
+
+		_, err = os.Stat(filepath.Join(outputDir, "Memes", defaultFileName(t, changedDrop)+".png"))
+		assert.True(t, os.IsNotExist(err))
+
+		_, err = os.Stat(filepath.Join(outputDir, "Memes", defaultFileName(t, changedDrop)+".info.json"))
+		assert.True(t, os.IsNotExist(err))
+
+		_, err = os.Stat(filepath.Join(outputDir, "Memes", defaultFileName(t, renamedDrop)+".png"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("WithState_KeepsFilesWhenTheReplacementFails", func(t *testing.T) {
+		t.Parallel()
+
+		dl, rdClient := setupTestDownloader(t)
+		imageServer := setupImageServer(t)
+		outputDir := t.TempDir()
+		collectionID := 123
+
+		rdClient.On("GetCollectionByID", mock.Anything, collectionID).Return(&raindrop.CollectionItem{
+			ID:    int64(collectionID),
+			Title: "Memes",
+		}, nil)
+
+		drop := raindrop.Drop{
+			ID:         7,
+			Title:      "Image 7",
+			Cover:      imageServer.URL + "/image7.png",
+			LastUpdate: "2024-09-22T21:37:53.067Z",
+		}
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(&raindrop.DropsPage{
+			Items: []raindrop.Drop{drop},
+		}, nil).Once()
+
+		mustDownloadCollection(t, dl, context.Background(), collectionID, outputDir, true)
+
+		// Second run: the drop was edited, and its cover vanished
+		edited := drop
+		edited.LastUpdate = "2024-10-01T10:00:00.000Z"
+		edited.Cover = imageServer.URL + "/missing.png"
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(&raindrop.DropsPage{
+			Items: []raindrop.Drop{edited},
+		}, nil).Once()
+
+		result, err := dl.DownloadCollection(context.Background(), collectionID, outputDir, true)
+		require.ErrorIs(t, err, downloader.ErrPartialFailure)
+		assert.Equal(t, 1, result.Failed)
+
+		for _, ext := range []string{".png", ".info.json"} {
+			_, err = os.Stat(filepath.Join(outputDir, "Memes", defaultFileName(t, drop)+ext))
+			assert.NoError(t, err, "the previous %s file is kept", ext)
+		}
+
+		// Third run: the cover is back, so the file is replaced
+		edited.Cover = imageServer.URL + "/image7.png"
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(&raindrop.DropsPage{
+			Items: []raindrop.Drop{edited},
+		}, nil).Once()
+
+		result = mustDownloadCollection(t, dl, context.Background(), collectionID, outputDir, true)
+		assert.Equal(t, 1, result.Downloaded)
+		assert.Equal(t, int64(len(pngBytes)), result.Bytes)
+	})
+
+	t.Run("WithState_RewritesTheInfoFileOfAChangedDrop", func(t *testing.T) {
+		t.Parallel()
+
+		dl, rdClient := setupTestDownloader(t)
+		imageServer := setupImageServer(t)
+		outputDir := t.TempDir()
+		collectionID := 123
+
+		rdClient.On("GetCollectionByID", mock.Anything, collectionID).Return(&raindrop.CollectionItem{
+			ID:    int64(collectionID),
+			Title: "Memes",
+		}, nil)
+
+		drop := raindrop.Drop{
+			ID:         8,
+			Title:      "Image 8",
+			Tags:       []string{"old"},
+			Cover:      imageServer.URL + "/image8.png",
+			LastUpdate: "2024-09-22T21:37:53.067Z",
+		}
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(&raindrop.DropsPage{
+			Items: []raindrop.Drop{drop},
+		}, nil).Once()
+
+		mustDownloadCollection(t, dl, context.Background(), collectionID, outputDir, true)
+
+		// Second run: the tags of the drop were edited, which keeps its file name
+		edited := drop
+		edited.Tags = []string{"new"}
+		edited.LastUpdate = "2024-10-01T10:00:00.000Z"
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(&raindrop.DropsPage{
+			Items: []raindrop.Drop{edited},
+		}, nil).Once()
+
+		mustDownloadCollection(t, dl, context.Background(), collectionID, outputDir, true)
+
+		data, err := os.ReadFile(filepath.Join(outputDir, "Memes", defaultFileName(t, drop)+".info.json"))
+		require.NoError(t, err)
+
+		var info downloader.InfoFile
+		require.NoError(t, json.Unmarshal(data, &info))
+		assert.Equal(t, []string{"new"}, info.Tags)
+		assert.Equal(t, []string{defaultFileName(t, drop) + ".png"}, info.Files)
+	})
+
+	t.Run("WithAllMedia_DownloadsEveryMedia", func(t *testing.T) {
+		t.Parallel()
+
+		rdClient := &MockRaindropClient{}
+		dl, err := downloader.NewDownloader(
+			downloader.WithRaindropClient(rdClient),
+			downloader.WithAllMedia(true),
+		)
+		require.NoError(t, err)
+
+		imageServer := setupImageServer(t)
+		outputDir := t.TempDir()
+		collectionID := 123
+
+		rdClient.On("GetCollectionByID", mock.Anything, collectionID).Return(&raindrop.CollectionItem{
+			ID: int64(collectionID),
+		}, nil)
+
+		mockDrop := raindrop.Drop{
+			ID:    1,
+			Title: "Gallery",
+			Cover: imageServer.URL + "/cover.png",
+			Media: []raindrop.MediaItem{
+				{Link: imageServer.URL + "/cover.png"},
+				{Link: imageServer.URL + "/second.png"},
+				{Link: imageServer.URL + "/third.png"},
+			},
+		}
+
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(&raindrop.DropsPage{
+			Items: []raindrop.Drop{mockDrop},
+		}, nil)
+
+		_, err = dl.DownloadCollection(context.Background(), collectionID, outputDir, true)
+		require.NoError(t, err)
+
+		assert.Equal(t, int32(3), imageServer.requests.Load())
+		assert.FileExists(t, filepath.Join(outputDir, "1-gallery.png"))
+		assert.FileExists(t, filepath.Join(outputDir, "1-gallery_01.png"))
+		assert.FileExists(t, filepath.Join(outputDir, "1-gallery_02.png"))
+
+		infoFile, err := os.ReadFile(filepath.Join(outputDir, "1-gallery.info.json"))
+		require.NoError(t, err)
+
+		var infoFileContent downloader.InfoFile
+		require.NoError(t, json.Unmarshal(infoFile, &infoFileContent))
+		assert.Equal(t, []string{"1-gallery.png", "1-gallery_01.png", "1-gallery_02.png"}, infoFileContent.Files)
+	})
+
+	t.Run("WithNameCollision_DisambiguatesNames", func(t *testing.T) {
+		t.Parallel()
+
+		nameTmpl, err := downloader.ParseNameTemplate("{{.Title | slug}}")
+		require.NoError(t, err)
+
+		rdClient := &MockRaindropClient{}
+		dl, err := downloader.NewDownloader(
+			downloader.WithRaindropClient(rdClient),
+			downloader.WithNameTemplate(nameTmpl),
+			downloader.WithConcurrency(1),
+		)
+		require.NoError(t, err)
+
+		imageServer := setupImageServer(t)
+		outputDir := t.TempDir()
+		collectionID := 123
+
+		rdClient.On("GetCollectionByID", mock.Anything, collectionID).Return(&raindrop.CollectionItem{
+			ID:    int64(collectionID),
+			Title: "../../escaped",
+		}, nil)
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(&raindrop.DropsPage{
+			Items: []raindrop.Drop{
+				{ID: 1, Title: "Same title", Cover: imageServer.URL + "/1.png"},
+				{ID: 2, Title: "Same Title", Cover: imageServer.URL + "/2.png"},
+			},
+		}, nil)
+
+		_, err = dl.DownloadCollection(context.Background(), collectionID, outputDir, false)
+		require.NoError(t, err)
+
+		assert.FileExists(t, filepath.Join(outputDir, "_.._escaped", "same-title.png"))
+		assert.FileExists(t, filepath.Join(outputDir, "_.._escaped", "same-title-2.png"))
+	})
 }
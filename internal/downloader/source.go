@@ -0,0 +1,120 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+// Source defines where the image of a drop is downloaded from
+type Source string
+
+const (
+	// SourceCover downloads the cover of the drop, which may be a preview resized by Raindrop
+	SourceCover Source = "cover"
+	// SourceCache downloads the permanent copy of the drop, archived by Raindrop
+	SourceCache Source = "cache"
+	// SourceLink downloads the bookmarked link
+	SourceLink Source = "link"
+	// SourceAuto tries the permanent copy, then the link and finally the cover, until one succeeds
+	SourceAuto Source = "auto"
+)
+
+// errNoSourceURL is returned when a drop has no URL for a source
+var errNoSourceURL = errors.New("drop has no URL for source")
+
+// ParseSource converts a string into a Source
+func ParseSource(source string) (Source, error) {
+	switch Source(source) {
+	case SourceCover, SourceCache, SourceLink, SourceAuto:
+		return Source(source), nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidSource, source)
+}
+
+// fileSources are the sources tried for the drop types that bookmark a file, like videos or documents.
+// The link is tried first, to save the file itself rather than its preview.
+var fileSources = []Source{SourceLink, SourceCache, SourceCover}
+
+// usesSourceSetting reports whether the drops of the given type are downloaded from the configured source
+func usesSourceSetting(dropType raindrop.DropType) bool {
+	switch dropType {
+	case raindrop.DropTypeVideo, raindrop.DropTypeDocument, raindrop.DropTypeAudio, raindrop.DropTypeArticle:
+		return false
+	}
+	return true
+}
+
+// sources returns the sources to try for a drop, in order.
+// Images are downloaded from the configured source, videos, documents and audio files from their link,
+// falling back to their preview when the link is not a media file (ex: a video page), and articles from their cover.
+func (d *Downloader) sources(item raindrop.Drop) []Source {
+	switch {
+	case usesSourceSetting(item.Type):
+		return d.source.candidates()
+	case item.Type == raindrop.DropTypeArticle:
+		return []Source{SourceCover}
+	}
+	return fileSources
+}
+
+// candidates returns the sources to try, in order
+func (s Source) candidates() []Source {
+	if s == SourceAuto {
+		return []Source{SourceCache, SourceLink, SourceCover}
+	}
+	return []Source{s}
+}
+
+// sourceURL returns the URL of the drop file for the given source
+func (d *Downloader) sourceURL(ctx context.Context, item raindrop.Drop, source Source) (string, error) {
+	var url string
+
+	switch source {
+	case SourceCache:
+		return d.rdClient.GetPermanentCopyURL(ctx, item.ID)
+	case SourceLink:
+		url = item.Link
+	default:
+		url = item.GetFileLink()
+	}
+
+	if url == "" {
+		return "", fmt.Errorf("%w %s", errNoSourceURL, source)
+	}
+	return url, nil
+}
+
+// downloadFromSource downloads the main file of a drop, trying each candidate source in order until one succeeds.
+// It returns errNoSourceURL if the drop has no URL for any of the sources.
+func (d *Downloader) downloadFromSource(ctx context.Context, item raindrop.Drop, dest string, replaced fileSet) (*downloadedFile, error) {
+	var errs []error
+	noURL := true
+
+	for _, source := range d.sources(item) {
+		url, err := d.sourceURL(ctx, item, source)
+		if err == nil {
+			var file *downloadedFile
+			if file, err = downloadFile(ctx, d.mediaTypes, url, dest, replaced); err == nil {
+				return file, nil
+			}
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		slog.Debug("Failed to download from source", "title", item.Title, "source", source, "error", err)
+		errs = append(errs, fmt.Errorf("%s: %w", source, err))
+		noURL = noURL && errors.Is(err, errNoSourceURL)
+	}
+
+	if noURL {
+		return nil, errNoSourceURL
+	}
+
+	return nil, errors.Join(errs...)
+}
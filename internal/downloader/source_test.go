@@ -0,0 +1,116 @@
+package downloader_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/raindrop-images-dl/internal/downloader"
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+func TestParseSource(t *testing.T) {
+	t.Parallel()
+
+	source, err := downloader.ParseSource("auto")
+	require.NoError(t, err)
+	assert.Equal(t, downloader.SourceAuto, source)
+
+	_, err = downloader.ParseSource("thumbnail")
+	assert.ErrorIs(t, err, downloader.ErrInvalidSource)
+}
+
+func TestDownloader_Source(t *testing.T) {
+	t.Parallel()
+
+	collectionID := 123
+
+	setup := func(t *testing.T, source downloader.Source) (*downloader.Downloader, *MockRaindropClient, *imageServer) {
+		t.Helper()
+
+		rdClient := &MockRaindropClient{}
+		dl, err := downloader.NewDownloader(
+			downloader.WithRaindropClient(rdClient),
+			downloader.WithSource(source),
+		)
+		require.NoError(t, err)
+
+		rdClient.On("GetCollectionByID", mock.Anything, collectionID).Return(&raindrop.CollectionItem{
+			ID: int64(collectionID),
+		}, nil)
+
+		return dl, rdClient, setupImageServer(t)
+	}
+
+	t.Run("WithCacheSource_DownloadsPermanentCopy", func(t *testing.T) {
+		t.Parallel()
+
+		dl, rdClient, imageServer := setup(t, downloader.SourceCache)
+		outputDir := t.TempDir()
+
+		mockDrop := raindrop.Drop{ID: 1, Title: "Image 1", Cover: imageServer.URL + "/missing-cover.png"}
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(&raindrop.DropsPage{
+			Items: []raindrop.Drop{mockDrop},
+		}, nil)
+		rdClient.On("GetPermanentCopyURL", mock.Anything, mockDrop.ID).Return(imageServer.URL+"/cache/1.png", nil)
+
+		mustDownloadCollection(t, dl, context.Background(), collectionID, outputDir, false)
+
+		assert.FileExists(t, filepath.Join(outputDir, "1-image-1.png"))
+		assert.Equal(t, int32(1), imageServer.requests.Load())
+	})
+
+	t.Run("WithAutoSource_FallsBackInOrder", func(t *testing.T) {
+		t.Parallel()
+
+		dl, rdClient, imageServer := setup(t, downloader.SourceAuto)
+		outputDir := t.TempDir()
+
+		mockDrop := raindrop.Drop{
+			ID:    1,
+			Title: "Image 1",
+			Link:  imageServer.URL + "/missing-link.png",
+			Cover: imageServer.URL + "/cover.png",
+		}
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(&raindrop.DropsPage{
+			Items: []raindrop.Drop{mockDrop},
+		}, nil)
+		rdClient.On("GetPermanentCopyURL", mock.Anything, mockDrop.ID).Return("", raindrop.ErrNotFound)
+
+		mustDownloadCollection(t, dl, context.Background(), collectionID, outputDir, false)
+
+		rdClient.AssertExpectations(t)
+		assert.FileExists(t, filepath.Join(outputDir, "1-image-1.png"))
+		// The link and then the cover were requested
+		assert.Equal(t, int32(2), imageServer.requests.Load())
+	})
+
+	t.Run("WithLinkSource_DoesNotFallBack", func(t *testing.T) {
+		t.Parallel()
+
+		dl, rdClient, imageServer := setup(t, downloader.SourceLink)
+		outputDir := t.TempDir()
+
+		mockDrop := raindrop.Drop{
+			ID:    1,
+			Title: "Image 1",
+			Link:  imageServer.URL + "/missing-link.png",
+			Cover: imageServer.URL + "/cover.png",
+		}
+		rdClient.On("GetDropsFromCollection", mock.Anything, collectionID, 0, mock.Anything).Return(&raindrop.DropsPage{
+			Items: []raindrop.Drop{mockDrop},
+		}, nil)
+
+		result, err := dl.DownloadCollection(context.Background(), collectionID, outputDir, false)
+		require.ErrorIs(t, err, downloader.ErrPartialFailure)
+		assert.Equal(t, 1, result.Failed)
+
+		_, err = os.Stat(filepath.Join(outputDir, "1-image-1.png"))
+		assert.True(t, os.IsNotExist(err))
+	})
+}
@@ -0,0 +1,161 @@
+package downloader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// StateFileName is the name of the file, stored at the root of the output directory,
+// that records the drops already downloaded, so that following runs only fetch new or changed drops.
+const StateFileName = ".raindrop-images-dl.state.json"
+
+// stateEntry records a downloaded drop
+type stateEntry struct {
+	// Path of the saved file, relative to the output directory
+	Path       string `json:"path"`
+	Checksum   string `json:"checksum"`
+	LastUpdate string `json:"last_update"`
+	SourceURL  string `json:"source_url"`
+	// Source is the source setting the drop was downloaded with. Empty for the cover.
+	Source string `json:"source,omitempty"`
+	// Assets are the paths of the additional media files of the drop, relative to the output directory
+	Assets []string `json:"assets,omitempty"`
+}
+
+// files returns the paths of all the files saved for the drop
+func (e stateEntry) files() []string {
+	return append([]string{e.Path}, e.Assets...)
+}
+
+// resolve returns the entry with its paths joined to dir
+func (e stateEntry) resolve(dir string) stateEntry {
+	e.Path = filepath.Join(dir, e.Path)
+	e.Assets = joinPaths(dir, e.Assets)
+	return e
+}
+
+func joinPaths(dir string, paths []string) []string {
+	if paths == nil {
+		return nil
+	}
+
+	joined := make([]string, 0, len(paths))
+	for _, p := range paths {
+		joined = append(joined, filepath.Join(dir, p))
+	}
+	return joined
+}
+
+// state is the sync state of an output directory, keyed by drop ID.
+// It is safe for concurrent use.
+type state struct {
+	mu    sync.Mutex
+	dir   string
+	Drops map[int64]stateEntry `json:"drops"`
+}
+
+// loadState reads the state file of the output directory. A missing file results in an empty state.
+func loadState(outputDir string) (*state, error) {
+	st := &state{
+		dir:   outputDir,
+		Drops: make(map[int64]stateEntry),
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, StateFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, fmt.Errorf("failed to decode state file: %w", err)
+	}
+
+	if st.Drops == nil {
+		st.Drops = make(map[int64]stateEntry)
+	}
+
+	return st, nil
+}
+
+// get returns the entry of a drop, with its path resolved against the output directory
+func (s *state) get(id int64) (stateEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.Drops[id]
+	if ok {
+		entry = entry.resolve(s.dir)
+	}
+	return entry, ok
+}
+
+// set records the entry of a drop. The entry paths are stored relative to the output directory.
+func (s *state) set(id int64, entry stateEntry) error {
+	relPath, err := filepath.Rel(s.dir, entry.Path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path relative to output directory: %w", err)
+	}
+	entry.Path = relPath
+
+	for i, asset := range entry.Assets {
+		if entry.Assets[i], err = filepath.Rel(s.dir, asset); err != nil {
+			return fmt.Errorf("failed to resolve path relative to output directory: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Drops[id] = entry
+	return nil
+}
+
+// remove deletes the entry of a drop
+func (s *state) remove(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.Drops, id)
+}
+
+// entries returns a copy of all entries, with their paths resolved against the output directory
+func (s *state) entries() map[int64]stateEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make(map[int64]stateEntry, len(s.Drops))
+	for id, entry := range s.Drops {
+		entries[id] = entry.resolve(s.dir)
+	}
+	return entries
+}
+
+// save writes the state file, replacing it atomically so that an interrupted write does not corrupt it.
+func (s *state) save() error {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode state file: %w", err)
+	}
+
+	statePath := filepath.Join(s.dir, StateFileName)
+	tmpPath := statePath + ".tmp"
+
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, statePath); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	return nil
+}
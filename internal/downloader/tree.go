@@ -0,0 +1,133 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/brpaz/raindrop-images-dl/internal/filename"
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+// UnsortedCollectionTitle is the title given to the Unsorted collection, which has none in the Raindrop API
+const UnsortedCollectionTitle = "Unsorted"
+
+// CollectionFilter selects the collections to download by title or ID.
+// A collection matches a list when its title or ID, or the title or ID of one of its ancestors, is in it.
+// Titles are compared case-insensitively.
+type CollectionFilter struct {
+	// Include lists the collections to download. When empty, all collections are downloaded.
+	Include []string
+	// Exclude lists the collections to skip. It takes precedence over Include.
+	Exclude []string
+}
+
+// matchesCollection reports whether a collection is listed by its title or ID
+func matchesCollection(list []string, c raindrop.CollectionItem) bool {
+	id := strconv.FormatInt(c.ID, 10)
+	for _, v := range list {
+		if v == id || strings.EqualFold(v, c.Title) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectionDir is a collection to download, with the directory it is saved to, relative to the output directory
+type collectionDir struct {
+	Collection raindrop.CollectionItem
+	Path       string
+}
+
+// collectionTree returns the root collection followed by all its nested collections, parents before their children.
+func (d *Downloader) collectionTree(ctx context.Context, root raindrop.CollectionItem) ([]collectionDir, error) {
+	nested, err := d.rdClient.GetChildCollections(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get child collections: %w", err)
+	}
+
+	return d.buildCollectionTree([]raindrop.CollectionItem{root}, nested), nil
+}
+
+// accountTree returns all the collections of the account, including the Unsorted collection, parents before their children.
+func (d *Downloader) accountTree(ctx context.Context) ([]collectionDir, error) {
+	roots, err := d.rdClient.GetRootCollections(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get root collections: %w", err)
+	}
+
+	nested, err := d.rdClient.GetChildCollections(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get child collections: %w", err)
+	}
+
+	roots = append(roots, raindrop.CollectionItem{ID: raindrop.UnsortedCollectionID, Title: UnsortedCollectionTitle})
+
+	return d.buildCollectionTree(roots, nested), nil
+}
+
+// buildCollectionTree walks the tree formed by the root collections and the nested collections
+// under them, returning the collections selected by the filter, parents before their children.
+// Each collection is saved in a sub-directory of its parent, named after its title.
+// Excluded collections are skipped with all their nested collections.
+func (d *Downloader) buildCollectionTree(roots, nested []raindrop.CollectionItem) []collectionDir {
+	type node struct {
+		dir      collectionDir
+		included bool
+	}
+
+	children := make(map[int64][]raindrop.CollectionItem)
+	for _, c := range nested {
+		parentID := int64(c.Parent.ID)
+		children[parentID] = append(children[parentID], c)
+	}
+
+	var queue []node
+	visited := make(map[int64]struct{})
+	paths := make(map[string]struct{})
+
+	add := func(c raindrop.CollectionItem, parentPath string, parentIncluded bool) {
+		// Guard against cycles, which would otherwise never end
+		if _, ok := visited[c.ID]; ok {
+			return
+		}
+		visited[c.ID] = struct{}{}
+
+		if matchesCollection(d.filter.Exclude, c) {
+			return
+		}
+
+		// Sibling collections with the same title must not share a directory, as they would prune each other's files
+		path := filepath.Join(parentPath, filename.Sanitize(c.Title))
+		if _, ok := paths[strings.ToLower(path)]; ok {
+			path = fmt.Sprintf("%s-%d", path, c.ID)
+		}
+		paths[strings.ToLower(path)] = struct{}{}
+
+		queue = append(queue, node{
+			dir:      collectionDir{Collection: c, Path: path},
+			included: parentIncluded || len(d.filter.Include) == 0 || matchesCollection(d.filter.Include, c),
+		})
+	}
+
+	for _, root := range roots {
+		add(root, "", false)
+	}
+
+	// The queue grows while it is walked, so every added collection has its own children looked up
+	var tree []collectionDir
+	for i := 0; i < len(queue); i++ {
+		n := queue[i]
+		if n.included {
+			tree = append(tree, n.dir)
+		}
+
+		for _, child := range children[n.dir.Collection.ID] {
+			add(child, n.dir.Path, n.included)
+		}
+	}
+
+	return tree
+}
@@ -0,0 +1,220 @@
+package downloader_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/raindrop-images-dl/internal/downloader"
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+// setupTreeTest returns a mock client serving a "Memes" collection with a nested "Reaction GIFs" collection,
+// itself holding a "Cats" collection, and a collection outside the tree. Each collection has a single drop.
+func setupTreeTest(t *testing.T, opts ...downloader.Option) (*downloader.Downloader, *MockRaindropClient) {
+	t.Helper()
+
+	imageServer := setupImageServer(t)
+
+	rdClient := &MockRaindropClient{}
+	dl, err := downloader.NewDownloader(append([]downloader.Option{downloader.WithRaindropClient(rdClient)}, opts...)...)
+	require.NoError(t, err)
+
+	rdClient.On("GetCollectionByID", mock.Anything, 1).Return(&raindrop.CollectionItem{ID: 1, Title: "Memes"}, nil)
+	rdClient.On("GetChildCollections", mock.Anything).Return([]raindrop.CollectionItem{
+		{ID: 3, Title: "Cats", Parent: raindrop.Ref{ID: 2}},
+		{ID: 2, Title: "Reaction GIFs", Parent: raindrop.Ref{ID: 1}},
+		{ID: 4, Title: "Wallpapers", Parent: raindrop.Ref{ID: 99}},
+	}, nil).Maybe()
+
+	for id, title := range map[int]string{1: "Root", 2: "Reaction", 3: "Cat"} {
+		rdClient.On("GetDropsFromCollection", mock.Anything, id, 0, mock.Anything).Return(&raindrop.DropsPage{
+			Items: []raindrop.Drop{{ID: int64(id * 10), Title: title, Cover: imageServer.URL + "/image.png"}},
+		}, nil).Maybe()
+	}
+
+	return dl, rdClient
+}
+
+func TestDownloader_Recursive(t *testing.T) {
+	t.Parallel()
+
+	t.Run("DownloadsNestedCollections_IntoSubDirectories", func(t *testing.T) {
+		t.Parallel()
+
+		dl, rdClient := setupTreeTest(t, downloader.WithRecursive(true))
+		outputDir := t.TempDir()
+
+		mustDownloadCollection(t, dl, context.Background(), 1, outputDir, false)
+
+		assert.FileExists(t, filepath.Join(outputDir, "Memes", "10-root.png"))
+		assert.FileExists(t, filepath.Join(outputDir, "Memes", "Reaction GIFs", "20-reaction.png"))
+		assert.FileExists(t, filepath.Join(outputDir, "Memes", "Reaction GIFs", "Cats", "30-cat.png"))
+		assert.NoDirExists(t, filepath.Join(outputDir, "Memes", "Wallpapers"))
+		rdClient.AssertNotCalled(t, "GetDropsFromCollection", mock.Anything, 4, mock.Anything, mock.Anything)
+	})
+
+	t.Run("WithoutRecursive_IgnoresNestedCollections", func(t *testing.T) {
+		t.Parallel()
+
+		dl, rdClient := setupTreeTest(t)
+		outputDir := t.TempDir()
+
+		mustDownloadCollection(t, dl, context.Background(), 1, outputDir, false)
+
+		assert.FileExists(t, filepath.Join(outputDir, "Memes", "10-root.png"))
+		assert.NoDirExists(t, filepath.Join(outputDir, "Memes", "Reaction GIFs"))
+		rdClient.AssertNotCalled(t, "GetChildCollections", mock.Anything)
+	})
+
+	t.Run("WithPrune_KeepsFilesOfNestedCollections", func(t *testing.T) {
+		t.Parallel()
+
+		dl, _ := setupTreeTest(t, downloader.WithRecursive(true), downloader.WithPruneMode(downloader.PruneDelete))
+		outputDir := t.TempDir()
+
+		mustDownloadCollection(t, dl, context.Background(), 1, outputDir, false)
+		mustDownloadCollection(t, dl, context.Background(), 1, outputDir, false)
+
+		assert.FileExists(t, filepath.Join(outputDir, "Memes", "10-root.png"))
+		assert.FileExists(t, filepath.Join(outputDir, "Memes", "Reaction GIFs", "20-reaction.png"))
+		assert.FileExists(t, filepath.Join(outputDir, "Memes", "Reaction GIFs", "Cats", "30-cat.png"))
+	})
+
+	t.Run("WhenChildCollectionsFail_ReturnsError", func(t *testing.T) {
+		t.Parallel()
+
+		rdClient := &MockRaindropClient{}
+		dl, err := downloader.NewDownloader(downloader.WithRaindropClient(rdClient), downloader.WithRecursive(true))
+		require.NoError(t, err)
+
+		rdClient.On("GetCollectionByID", mock.Anything, 1).Return(&raindrop.CollectionItem{ID: 1, Title: "Memes"}, nil)
+		rdClient.On("GetChildCollections", mock.Anything).Return([]raindrop.CollectionItem(nil), errors.New("boom"))
+
+		_, err = dl.DownloadCollection(context.Background(), 1, t.TempDir(), false)
+		assert.ErrorContains(t, err, "failed to get child collections")
+	})
+}
+
+// setupAccountTest returns a mock client serving an account with the "Memes" tree of setupTreeTest,
+// a "Wallpapers" root collection and the Unsorted collection. Each collection has a single drop.
+func setupAccountTest(t *testing.T, opts ...downloader.Option) (*downloader.Downloader, *MockRaindropClient) {
+	t.Helper()
+
+	imageServer := setupImageServer(t)
+
+	rdClient := &MockRaindropClient{}
+	dl, err := downloader.NewDownloader(append([]downloader.Option{downloader.WithRaindropClient(rdClient)}, opts...)...)
+	require.NoError(t, err)
+
+	rdClient.On("GetRootCollections", mock.Anything).Return([]raindrop.CollectionItem{
+		{ID: 1, Title: "Memes"},
+		{ID: 4, Title: "Wallpapers"},
+	}, nil)
+	rdClient.On("GetChildCollections", mock.Anything).Return([]raindrop.CollectionItem{
+		{ID: 2, Title: "Reaction GIFs", Parent: raindrop.Ref{ID: 1}},
+		{ID: 3, Title: "Cats", Parent: raindrop.Ref{ID: 2}},
+	}, nil)
+
+	titles := map[int]string{1: "Root", 2: "Reaction", 3: "Cat", 4: "Mountain", raindrop.UnsortedCollectionID: "Loose"}
+	for id, title := range titles {
+		rdClient.On("GetDropsFromCollection", mock.Anything, id, 0, mock.Anything).Return(&raindrop.DropsPage{
+			Items: []raindrop.Drop{{ID: int64(100 + id), Title: title, Cover: imageServer.URL + "/image.png"}},
+		}, nil).Maybe()
+	}
+
+	return dl, rdClient
+}
+
+func TestDownloader_DownloadAllCollections(t *testing.T) {
+	t.Parallel()
+
+	t.Run("WithEmptyOutputDir_ReturnsError", func(t *testing.T) {
+		t.Parallel()
+
+		dl, _ := setupTestDownloader(t)
+
+		_, err := dl.DownloadAllCollections(context.Background(), "", false)
+		assert.ErrorIs(t, err, downloader.ErrOutputDirNotSet)
+	})
+
+	t.Run("DownloadsEveryCollection", func(t *testing.T) {
+		t.Parallel()
+
+		dl, _ := setupAccountTest(t)
+		outputDir := t.TempDir()
+
+		mustDownloadAllCollections(t, dl, context.Background(), outputDir, false)
+
+		assert.FileExists(t, filepath.Join(outputDir, "Memes", "101-root.png"))
+		assert.FileExists(t, filepath.Join(outputDir, "Memes", "Reaction GIFs", "102-reaction.png"))
+		assert.FileExists(t, filepath.Join(outputDir, "Memes", "Reaction GIFs", "Cats", "103-cat.png"))
+		assert.FileExists(t, filepath.Join(outputDir, "Wallpapers", "104-mountain.png"))
+		assert.FileExists(t, filepath.Join(outputDir, downloader.UnsortedCollectionTitle, "99-loose.png"))
+	})
+
+	t.Run("WithInclude_DownloadsMatchingCollectionsAndTheirChildren", func(t *testing.T) {
+		t.Parallel()
+
+		dl, rdClient := setupAccountTest(t, downloader.WithCollectionFilter(downloader.CollectionFilter{
+			Include: []string{"reaction gifs", "4"},
+		}))
+		outputDir := t.TempDir()
+
+		mustDownloadAllCollections(t, dl, context.Background(), outputDir, false)
+
+		assert.FileExists(t, filepath.Join(outputDir, "Memes", "Reaction GIFs", "102-reaction.png"))
+		assert.FileExists(t, filepath.Join(outputDir, "Memes", "Reaction GIFs", "Cats", "103-cat.png"))
+		assert.FileExists(t, filepath.Join(outputDir, "Wallpapers", "104-mountain.png"))
+		assert.NoFileExists(t, filepath.Join(outputDir, "Memes", "101-root.png"))
+		rdClient.AssertNotCalled(t, "GetDropsFromCollection", mock.Anything, raindrop.UnsortedCollectionID, mock.Anything, mock.Anything)
+	})
+
+	t.Run("WithExclude_SkipsMatchingCollectionsAndTheirChildren", func(t *testing.T) {
+		t.Parallel()
+
+		dl, rdClient := setupAccountTest(t, downloader.WithCollectionFilter(downloader.CollectionFilter{
+			Exclude: []string{"2", "Unsorted"},
+		}))
+		outputDir := t.TempDir()
+
+		mustDownloadAllCollections(t, dl, context.Background(), outputDir, false)
+
+		assert.FileExists(t, filepath.Join(outputDir, "Memes", "101-root.png"))
+		assert.FileExists(t, filepath.Join(outputDir, "Wallpapers", "104-mountain.png"))
+		assert.NoDirExists(t, filepath.Join(outputDir, "Memes", "Reaction GIFs"))
+		assert.NoDirExists(t, filepath.Join(outputDir, downloader.UnsortedCollectionTitle))
+		rdClient.AssertNotCalled(t, "GetDropsFromCollection", mock.Anything, 3, mock.Anything, mock.Anything)
+	})
+
+	t.Run("WithSameTitleSiblings_UsesDistinctDirectories", func(t *testing.T) {
+		t.Parallel()
+
+		imageServer := setupImageServer(t)
+		rdClient := &MockRaindropClient{}
+		dl, err := downloader.NewDownloader(downloader.WithRaindropClient(rdClient))
+		require.NoError(t, err)
+
+		rdClient.On("GetRootCollections", mock.Anything).Return([]raindrop.CollectionItem{
+			{ID: 1, Title: "Memes"},
+			{ID: 2, Title: "memes"},
+		}, nil)
+		rdClient.On("GetChildCollections", mock.Anything).Return([]raindrop.CollectionItem{}, nil)
+		for _, id := range []int{1, 2, raindrop.UnsortedCollectionID} {
+			rdClient.On("GetDropsFromCollection", mock.Anything, id, 0, mock.Anything).Return(&raindrop.DropsPage{
+				Items: []raindrop.Drop{{ID: int64(100 + id), Title: "Image", Cover: imageServer.URL + "/image.png"}},
+			}, nil)
+		}
+
+		outputDir := t.TempDir()
+		mustDownloadAllCollections(t, dl, context.Background(), outputDir, false)
+
+		assert.FileExists(t, filepath.Join(outputDir, "Memes", "101-image.png"))
+		assert.FileExists(t, filepath.Join(outputDir, "memes-2", "102-image.png"))
+	})
+}
@@ -1,59 +1,18 @@
 package downloader
 
 import (
+	"errors"
 	"fmt"
-	"io"
 	"log/slog"
-	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
-// MIME type to file extension map
-var mimeMap = map[string]string{
-	"image/jpeg":               ".jpg",
-	"image/png":                ".png",
-	"image/gif":                ".gif",
-	"image/bmp":                ".bmp",
-	"image/webp":               ".webp",
-	"image/svg+xml":            ".svg",
-	"application/pdf":          ".pdf",
-	"text/plain":               ".txt",
-	"application/octet-stream": ".bin",
-}
-
-// DownloadFile downloads a file from a URL and saves it to the destination path.
-func downloadFile(url, dest string) error {
-	resp, err := http.Get(url) // #nosec
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download file: %s", resp.Status)
-	}
-
-	contentType := resp.Header.Get("Content-Type")
-	extension, supported := mimeMap[contentType]
-	if !supported {
-		return fmt.Errorf("unsupported content type: %s", contentType)
-	}
-
-	dest += extension
-
-	if fileExists(dest) {
-		slog.Info("File already exists, skipping", "path", dest)
-		return nil
-	}
-
-	out, err := os.Create(dest)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	_, err = io.Copy(out, resp.Body)
-	return err
+// fileExists checks if a file already exists at the specified path.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return !os.IsNotExist(err)
 }
 
 // dirExists checks if a directory exists.
@@ -62,12 +21,6 @@ func dirExists(path string) bool {
 	return err == nil && info.IsDir()
 }
 
-// fileExists checks if a file already exists at the specified path.
-func fileExists(path string) bool {
-	_, err := os.Stat(path)
-	return !os.IsNotExist(err)
-}
-
 // ensureDir ensures that a directory exists, creating it if necessary.
 func ensureDir(dir string) error {
 	if !dirExists(dir) {
@@ -77,3 +30,32 @@ func ensureDir(dir string) error {
 	}
 	return nil
 }
+
+// fileSet is a set of file paths
+type fileSet map[string]bool
+
+func newFileSet(paths ...string) fileSet {
+	set := make(fileSet, len(paths))
+	for _, p := range paths {
+		set[p] = true
+	}
+	return set
+}
+
+// removeStaleFiles removes the files of a previous download of a drop, and its info file, except the ones to keep
+func removeStaleFiles(stale stateEntry, keep []string) {
+	kept := newFileSet(keep...)
+	for _, p := range append(stale.files(), trimExt(stale.Path)+".info.json") {
+		if kept[p] {
+			continue
+		}
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("Failed to remove stale file", "path", p, "error", err)
+		}
+	}
+}
+
+// trimExt returns the path without its file extension
+func trimExt(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path))
+}
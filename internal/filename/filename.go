@@ -0,0 +1,63 @@
+// Package filename provides helpers to build file names that are valid on every platform.
+package filename
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxBytes is the maximum length of a sanitized name, in bytes.
+// Most file systems limit names to 255 bytes, so room is left for suffixes and extensions, like "_01.jpeg.info.json".
+const MaxBytes = 200
+
+// reservedChars are the characters not allowed in file names on Windows or any other platform
+const reservedChars = `/\:*?"<>|`
+
+// reservedNames are the device names reserved by Windows, with or without extension
+var reservedNames = map[string]struct{}{
+	"CON": {}, "PRN": {}, "AUX": {}, "NUL": {},
+	"COM1": {}, "COM2": {}, "COM3": {}, "COM4": {}, "COM5": {}, "COM6": {}, "COM7": {}, "COM8": {}, "COM9": {},
+	"LPT1": {}, "LPT2": {}, "LPT3": {}, "LPT4": {}, "LPT5": {}, "LPT6": {}, "LPT7": {}, "LPT8": {}, "LPT9": {},
+}
+
+// Sanitize converts a string into a name that is safe to use as a file name on any platform.
+// Path separators, reserved and control characters are replaced by underscores, leading and
+// trailing dots and spaces are removed, Unicode is normalized to NFC, Windows reserved names are
+// suffixed with an underscore and the result is truncated to MaxBytes on a rune boundary.
+// An empty string is returned if nothing usable is left.
+func Sanitize(name string) string {
+	name = norm.NFC.String(name)
+
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == utf8.RuneError || strings.ContainsRune(reservedChars, r) {
+			return '_'
+		}
+		return r
+	}, name)
+
+	name = truncate(strings.Trim(name, ". "), MaxBytes)
+
+	// Truncation may leave a trailing dot or space, which Windows does not allow
+	name = strings.TrimRight(name, ". ")
+
+	base, _, _ := strings.Cut(name, ".")
+	if _, reserved := reservedNames[strings.ToUpper(strings.TrimSpace(base))]; reserved {
+		name = base + "_" + strings.TrimPrefix(name, base)
+	}
+
+	return name
+}
+
+// truncate shortens s to at most maxBytes, without splitting a multi-byte rune.
+func truncate(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+
+	for maxBytes > 0 && !utf8.RuneStart(s[maxBytes]) {
+		maxBytes--
+	}
+	return s[:maxBytes]
+}
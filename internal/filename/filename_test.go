@@ -0,0 +1,53 @@
+package filename_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/brpaz/raindrop-images-dl/internal/filename"
+)
+
+func TestSanitize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "Plain", input: "Funny_cat", expected: "Funny_cat"},
+		{name: "PathTraversal", input: "../../x", expected: "_.._x"},
+		{name: "ReservedChars", input: `a/b\c:d*e?f"g<h>i|j`, expected: "a_b_c_d_e_f_g_h_i_j"},
+		{name: "ControlChars", input: "a\x00b\tc", expected: "a_b_c"},
+		{name: "TrailingDotsAndSpaces", input: "title. . ", expected: "title"},
+		{name: "LeadingDots", input: ".hidden", expected: "hidden"},
+		{name: "DotDot", input: "..", expected: ""},
+		{name: "WindowsReservedName", input: "CON", expected: "CON_"},
+		{name: "WindowsReservedNameLowercase", input: "aux", expected: "aux_"},
+		{name: "WindowsReservedNameWithExtension", input: "nul.txt", expected: "nul_.txt"},
+		{name: "NotReservedName", input: "CONSOLE", expected: "CONSOLE"},
+		{name: "NFC", input: "café", expected: "café"},
+		{name: "Empty", input: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.expected, filename.Sanitize(tt.input))
+		})
+	}
+
+	t.Run("Truncate", func(t *testing.T) {
+		t.Parallel()
+
+		name := filename.Sanitize(strings.Repeat("é", 300))
+
+		assert.LessOrEqual(t, len(name), filename.MaxBytes)
+		assert.True(t, utf8.ValidString(name))
+		assert.Equal(t, strings.Repeat("é", filename.MaxBytes/2), name)
+	})
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Sync results, used as the result label of the syncs metric
+const (
+	syncSuccess = "success"
+	syncFailure = "failure"
+)
+
+var errNoSync = errors.New("no sync completed yet")
+
+// RecordSync records the outcome of a sync, which is successful when err is nil
+func (m *Metrics) RecordSync(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastSyncErr = err
+	if err != nil {
+		m.syncs.WithLabelValues(syncFailure).Inc()
+		return
+	}
+
+	m.syncs.WithLabelValues(syncSuccess).Inc()
+	m.lastSuccess.SetToCurrentTime()
+}
+
+// ready returns nil when the last sync succeeded, or the reason why the service is not ready
+func (m *Metrics) ready() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.lastSyncErr
+}
+
+// NewServeMux returns the handler of the metrics and health endpoints:
+//   - /metrics serves the Prometheus metrics.
+//   - /healthz reports that the process is running.
+//   - /readyz reports whether the last sync succeeded. It fails until the first sync completes.
+func (m *Metrics) NewServeMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if err := m.ready(); err != nil {
+			http.Error(w, "not ready: "+err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
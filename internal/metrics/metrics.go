@@ -0,0 +1,194 @@
+// Package metrics exposes the Prometheus metrics and the health endpoints of the long-running download mode.
+// The metrics are fed by the downloader and the Raindrop SDK client, as an observer of both.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/brpaz/raindrop-images-dl/internal/downloader"
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+const namespace = "raindrop_images_dl"
+
+// Item results, used as the result label of the items metric
+const (
+	ItemDownloaded = "downloaded"
+	ItemSkipped    = "skipped"
+	ItemFailed     = "failed"
+)
+
+// Metrics collects the metrics of the download runs and of the Raindrop API requests,
+// and tracks the outcome of the syncs for the readiness endpoint. It is safe for concurrent use.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	items                 *prometheus.CounterVec
+	bytes                 prometheus.Counter
+	apiRequests           *prometheus.CounterVec
+	rateLimitWaits        prometheus.Counter
+	rateLimitWaitSeconds  prometheus.Counter
+	syncs                 *prometheus.CounterVec
+	lastSuccess           prometheus.Gauge
+	collectionLastSuccess *prometheus.GaugeVec
+
+	mu sync.Mutex
+	// collection is the path of the collection being downloaded, whose success is recorded once it completes
+	collection string
+	// collectionFailures is the number of drops of the collection that failed to download
+	collectionFailures int
+	// lastSyncErr is the error of the last sync, or errNoSync before the first one
+	lastSyncErr error
+}
+
+// New creates the metrics, registered in their own registry with the Go runtime and process metrics
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		items: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "items_total",
+			Help:      "Number of drops processed, by result: downloaded, skipped or failed.",
+		}, []string{"result"}),
+		bytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "downloaded_bytes_total",
+			Help:      "Size of the downloaded files.",
+		}),
+		apiRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "api_requests_total",
+			Help:      "Number of requests sent to the Raindrop API, including retries, by status code. The code is \"error\" when no response was received.",
+		}, []string{"code"}),
+		rateLimitWaits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limit_waits_total",
+			Help:      "Number of times the client waited for the Raindrop API rate limit.",
+		}),
+		rateLimitWaitSeconds: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limit_wait_seconds_total",
+			Help:      "Time spent waiting for the Raindrop API rate limit.",
+		}),
+		syncs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "syncs_total",
+			Help:      "Number of completed syncs, by result: success or failure.",
+		}, []string{"result"}),
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_success_timestamp_seconds",
+			Help:      "Unix time of the last sync that completed without errors.",
+		}),
+		collectionLastSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "collection_last_success_timestamp_seconds",
+			Help:      "Unix time of the last sync of a collection that completed without errors, by collection path.",
+		}, []string{"collection"}),
+		lastSyncErr: errNoSync,
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.items,
+		m.bytes,
+		m.apiRequests,
+		m.rateLimitWaits,
+		m.rateLimitWaitSeconds,
+		m.syncs,
+		m.lastSuccess,
+		m.collectionLastSuccess,
+	)
+
+	// Export the results with a zero value, so that rates can be computed from the first sync
+	for _, result := range []string{ItemDownloaded, ItemSkipped, ItemFailed} {
+		m.items.WithLabelValues(result)
+	}
+	for _, result := range []string{syncSuccess, syncFailure} {
+		m.syncs.WithLabelValues(result)
+	}
+
+	return m
+}
+
+// Handler returns the handler of the /metrics endpoint
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Downloader returns the observer of the download runs
+func (m *Metrics) Downloader() downloader.Observer {
+	return downloaderObserver{m: m}
+}
+
+// Client returns the observer of the requests of the Raindrop SDK client
+func (m *Metrics) Client() raindrop.Observer {
+	return clientObserver{m: m}
+}
+
+// downloaderObserver records the outcome of the drops and collections
+type downloaderObserver struct {
+	downloader.NopObserver
+	m *Metrics
+}
+
+func (o downloaderObserver) OnCollectionStart(_ raindrop.CollectionItem, path string) {
+	o.m.mu.Lock()
+	defer o.m.mu.Unlock()
+
+	o.m.collection = path
+	o.m.collectionFailures = 0
+}
+
+func (o downloaderObserver) OnItemSkipped(raindrop.Drop, string) {
+	o.m.items.WithLabelValues(ItemSkipped).Inc()
+}
+
+func (o downloaderObserver) OnItemDownloaded(_ string, _ raindrop.Drop, bytes int64) {
+	o.m.items.WithLabelValues(ItemDownloaded).Inc()
+	o.m.bytes.Add(float64(bytes))
+}
+
+func (o downloaderObserver) OnItemFailed(raindrop.Drop, error) {
+	o.m.items.WithLabelValues(ItemFailed).Inc()
+
+	o.m.mu.Lock()
+	defer o.m.mu.Unlock()
+	o.m.collectionFailures++
+}
+
+// OnCollectionComplete records the time of the sync when all the drops of the collection were processed successfully
+func (o downloaderObserver) OnCollectionComplete(_ raindrop.CollectionItem, err error) {
+	o.m.mu.Lock()
+	defer o.m.mu.Unlock()
+
+	if err == nil && o.m.collectionFailures == 0 {
+		o.m.collectionLastSuccess.WithLabelValues(o.m.collection).SetToCurrentTime()
+	}
+}
+
+// clientObserver records the requests sent to the Raindrop API
+type clientObserver struct {
+	m *Metrics
+}
+
+func (o clientObserver) OnRequest(_ *http.Request, statusCode int) {
+	code := "error"
+	if statusCode != 0 {
+		code = strconv.Itoa(statusCode)
+	}
+	o.m.apiRequests.WithLabelValues(code).Inc()
+}
+
+func (o clientObserver) OnRateLimitWait(wait time.Duration) {
+	o.m.rateLimitWaits.Inc()
+	o.m.rateLimitWaitSeconds.Add(wait.Seconds())
+}
@@ -0,0 +1,121 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/raindrop-images-dl/internal/metrics"
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+// get requests a path of the server, returning the status code and the body
+func get(t *testing.T, server *httptest.Server, path string) (int, string) {
+	t.Helper()
+
+	resp, err := http.Get(server.URL + path)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp.StatusCode, string(body)
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	t.Run("RecordsDownloaderEvents", func(t *testing.T) {
+		t.Parallel()
+
+		m := metrics.New()
+		server := httptest.NewServer(m.NewServeMux())
+		defer server.Close()
+
+		observer := m.Downloader()
+		observer.OnCollectionStart(raindrop.CollectionItem{Title: "Memes"}, "Memes")
+		observer.OnItemDownloaded("Memes/1.png", raindrop.Drop{ID: 1}, 100)
+		observer.OnItemDownloaded("Memes/2.png", raindrop.Drop{ID: 2}, 50)
+		observer.OnItemSkipped(raindrop.Drop{ID: 3}, "Memes/3.png")
+		observer.OnCollectionComplete(raindrop.CollectionItem{Title: "Memes"}, nil)
+
+		observer.OnCollectionStart(raindrop.CollectionItem{Title: "Work"}, "Work")
+		observer.OnItemFailed(raindrop.Drop{ID: 4}, errors.New("not found"))
+		observer.OnCollectionComplete(raindrop.CollectionItem{Title: "Work"}, nil)
+
+		observer.OnCollectionStart(raindrop.CollectionItem{Title: "Cats"}, "Memes/Cats")
+		observer.OnCollectionComplete(raindrop.CollectionItem{Title: "Cats"}, context.Canceled)
+
+		status, body := get(t, server, "/metrics")
+		require.Equal(t, http.StatusOK, status)
+
+		assert.Contains(t, body, `raindrop_images_dl_items_total{result="downloaded"} 2`)
+		assert.Contains(t, body, `raindrop_images_dl_items_total{result="skipped"} 1`)
+		assert.Contains(t, body, `raindrop_images_dl_items_total{result="failed"} 1`)
+		assert.Contains(t, body, `raindrop_images_dl_downloaded_bytes_total 150`)
+		assert.Contains(t, body, `raindrop_images_dl_collection_last_success_timestamp_seconds{collection="Memes"}`)
+		assert.NotContains(t, body, `collection="Work"`, "a collection with failed drops is not a success")
+		assert.NotContains(t, body, `collection="Memes/Cats"`, "an interrupted collection is not a success")
+	})
+
+	t.Run("RecordsClientEvents", func(t *testing.T) {
+		t.Parallel()
+
+		m := metrics.New()
+		server := httptest.NewServer(m.NewServeMux())
+		defer server.Close()
+
+		req := httptest.NewRequest(http.MethodGet, "/rest/v1/user", nil)
+		observer := m.Client()
+		observer.OnRequest(req, http.StatusOK)
+		observer.OnRequest(req, http.StatusOK)
+		observer.OnRequest(req, http.StatusTooManyRequests)
+		observer.OnRequest(req, 0)
+		observer.OnRateLimitWait(1500 * time.Millisecond)
+
+		_, body := get(t, server, "/metrics")
+
+		assert.Contains(t, body, `raindrop_images_dl_api_requests_total{code="200"} 2`)
+		assert.Contains(t, body, `raindrop_images_dl_api_requests_total{code="429"} 1`)
+		assert.Contains(t, body, `raindrop_images_dl_api_requests_total{code="error"} 1`)
+		assert.Contains(t, body, `raindrop_images_dl_rate_limit_waits_total 1`)
+		assert.Contains(t, body, `raindrop_images_dl_rate_limit_wait_seconds_total 1.5`)
+	})
+
+	t.Run("ReadyAfterSuccessfulSync", func(t *testing.T) {
+		t.Parallel()
+
+		m := metrics.New()
+		server := httptest.NewServer(m.NewServeMux())
+		defer server.Close()
+
+		status, _ := get(t, server, "/healthz")
+		assert.Equal(t, http.StatusOK, status)
+
+		status, body := get(t, server, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+		assert.Contains(t, body, "no sync completed yet")
+
+		m.RecordSync(nil)
+		status, _ = get(t, server, "/readyz")
+		assert.Equal(t, http.StatusOK, status)
+
+		m.RecordSync(errors.New("collection 123 was not found"))
+		status, body = get(t, server, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+		assert.Contains(t, body, "collection 123 was not found")
+
+		_, body = get(t, server, "/metrics")
+		assert.Contains(t, body, `raindrop_images_dl_syncs_total{result="success"} 1`)
+		assert.Contains(t, body, `raindrop_images_dl_syncs_total{result="failure"} 1`)
+		assert.Contains(t, body, `raindrop_images_dl_last_success_timestamp_seconds `)
+	})
+}
@@ -5,25 +5,37 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
-)
+	"strconv"
+	"sync"
+	"time"
 
-const (
-	defaultBaseURL = "https://api.raindrop.io/rest/v1"
-	itemsPerPage   = 50 // Number of items to retrieve per page. Max is 50 according to the Raindrop API docs
+	"golang.org/x/oauth2"
 )
 
+// UnsortedCollectionID is the ID of the special collection holding the drops that are not in any collection
+const UnsortedCollectionID = -1
+
+const defaultBaseURL = "https://api.raindrop.io/rest/v1"
+
 var (
 	ErrMissingAPIKey  = errors.New("API key is required")
 	ErrInvalidBaseURL = errors.New("Invalid base URL")
+	ErrNoRedirect     = errors.New("permanent copy response has no redirect location")
+	ErrInvalidPerPage = errors.New("invalid page size")
 )
 
 // Client is a client for the Raindrop API
 type Client struct {
-	baseURL    string
-	apiKey     string
-	httpClient *http.Client
+	baseURL     string
+	apiKey      string
+	tokenSource oauth2.TokenSource
+	httpClient  *http.Client
+	retryPolicy RetryPolicy
+	observers   []Observer
+
+	mu               sync.Mutex
+	rateLimitResetAt time.Time
 }
 
 // Option defines a functional option type for configuring the Client
@@ -33,8 +45,9 @@ type Option func(*Client)
 func NewClient(opts ...Option) (*Client, error) {
 	// Set default values
 	client := &Client{
-		baseURL:    defaultBaseURL,
-		httpClient: http.DefaultClient,
+		baseURL:     defaultBaseURL,
+		httpClient:  http.DefaultClient,
+		retryPolicy: DefaultRetryPolicy,
 	}
 
 	// Apply any options passed in
@@ -42,7 +55,7 @@ func NewClient(opts ...Option) (*Client, error) {
 		opt(client)
 	}
 
-	if client.apiKey == "" {
+	if client.apiKey == "" && client.tokenSource == nil {
 		return nil, ErrMissingAPIKey
 	}
 
@@ -50,6 +63,13 @@ func NewClient(opts ...Option) (*Client, error) {
 		return nil, ErrInvalidBaseURL
 	}
 
+	// OAuth2 tokens are set by the transport, which refreshes them when needed
+	if client.tokenSource != nil {
+		httpClient := *client.httpClient
+		httpClient.Transport = &oauth2.Transport{Source: client.tokenSource, Base: client.httpClient.Transport}
+		client.httpClient = &httpClient
+	}
+
 	return client, nil
 }
 
@@ -73,13 +93,29 @@ func WithBaseURL(baseURL string) Option {
 	}
 }
 
-// setAuthHeader sets the Authorization header with the API key
+// setAuthHeader sets the Authorization header with the API key. With OAuth2, the header is set by the transport instead.
 func (c *Client) setAuthHeader(req *http.Request) {
+	if c.tokenSource != nil {
+		return
+	}
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
 }
 
-// GetImagesDropsFromCollection retrieves all image drops from a collection
+// GetImagesDropsFromCollection retrieves a page of the image drops from a collection.
+//
+// Deprecated: Use GetDropsFromCollection with a search on DropTypeImage, which also supports the other filters.
 func (c *Client) GetImagesDropsFromCollection(ctx context.Context, collectionID int, page int) (*ImageDrops, error) {
+	return c.GetDropsFromCollection(ctx, collectionID, page, ListOptions{Search: NewSearch().Type(DropTypeImage)})
+}
+
+// GetDropsFromCollection retrieves a page of the drops from a collection, matching the options.
+// Pages start at 0. Use Drops to iterate over all the drops instead.
+func (c *Client) GetDropsFromCollection(ctx context.Context, collectionID int, page int, opts ListOptions) (*DropsPage, error) {
+	perPage, err := opts.perPage()
+	if err != nil {
+		return nil, err
+	}
+
 	// Construct the URL for the Raindrop API
 	url := fmt.Sprintf("%s/raindrops/%d", c.baseURL, collectionID)
 
@@ -94,22 +130,26 @@ func (c *Client) GetImagesDropsFromCollection(ctx context.Context, collectionID
 
 	// Add query parameters
 	q := req.URL.Query()
-	q.Add("perpage", fmt.Sprintf("%d", itemsPerPage))
-	q.Add("page", fmt.Sprintf("%d", page))
-	q.Add(("search"), "type:image") // Filter for items of type "image"
+	q.Add("perpage", strconv.Itoa(perPage))
+	q.Add("page", strconv.Itoa(page))
+	if !opts.Search.IsZero() {
+		q.Add("search", opts.Search.String())
+	}
+	if opts.Sort != "" {
+		q.Add("sort", string(opts.Sort))
+	}
 	req.URL.RawQuery = q.Encode()
 
 	// Send the HTTP request
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to perform request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	// Check for a non-200 status code
 	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status code: %d, response: %s", resp.StatusCode, respBody)
+		return nil, newAPIError(resp)
 	}
 
 	// Parse the response body
@@ -118,19 +158,22 @@ func (c *Client) GetImagesDropsFromCollection(ctx context.Context, collectionID
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	// base on the page, count and itemsPerPage, determine if there are more items
-	totalItems := drops.Count
-	hasMore := true
-	if totalItems == 0 || totalItems <= page*itemsPerPage {
-		hasMore = false
-	}
+	// There are more items if the pages up to this one do not cover the total count.
+	// A short page means the end was reached, even if the count changed in the meantime.
+	hasMore := (page+1)*perPage < drops.Count && len(drops.Items) == perPage
 
-	return &ImageDrops{
+	return &DropsPage{
 		Items:   drops.Items,
 		HasMore: hasMore,
+		Count:   drops.Count,
 	}, nil
 }
 
+// Drops returns an iterator over all the drops of a collection matching the options
+func (c *Client) Drops(ctx context.Context, collectionID int, opts ListOptions) *DropIterator {
+	return NewDropIterator(ctx, c, collectionID, opts)
+}
+
 func (c *Client) GetCollectionByID(ctx context.Context, collectionID int) (*CollectionItem, error) {
 	url := fmt.Sprintf("%s/collection/%d", c.baseURL, collectionID)
 
@@ -141,15 +184,14 @@ func (c *Client) GetCollectionByID(ctx context.Context, collectionID int) (*Coll
 
 	c.setAuthHeader(req)
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to perform request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status code: %d, response: %s", resp.StatusCode, respBody)
+		return nil, newAPIError(resp)
 	}
 
 	var collection GetCollectionResponse
@@ -159,3 +201,110 @@ func (c *Client) GetCollectionByID(ctx context.Context, collectionID int) (*Coll
 
 	return &collection.Item, nil
 }
+
+// GetUser retrieves the user the API key belongs to.
+// It is a cheap call, which can be used to check that the API key is valid.
+func (c *Client) GetUser(ctx context.Context) (*User, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/user", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.setAuthHeader(req)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	var user GetUserResponse
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &user.User, nil
+}
+
+// GetRootCollections retrieves the top-level collections of the account
+func (c *Client) GetRootCollections(ctx context.Context) ([]CollectionItem, error) {
+	return c.getCollections(ctx, "/collections")
+}
+
+// GetChildCollections retrieves all nested collections of the account, at any depth.
+// The tree can be rebuilt from the Parent reference of each collection.
+func (c *Client) GetChildCollections(ctx context.Context) ([]CollectionItem, error) {
+	return c.getCollections(ctx, "/collections/childrens")
+}
+
+// getCollections retrieves a list of collections from the given endpoint
+func (c *Client) getCollections(ctx context.Context, path string) ([]CollectionItem, error) {
+	url := c.baseURL + path
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.setAuthHeader(req)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	var collections GetCollectionsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&collections); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return collections.Items, nil
+}
+
+// GetPermanentCopyURL returns the URL of the permanent copy of a drop, as stored by Raindrop.
+// The API redirects to the location of the archived file, which is returned without being downloaded.
+// Permanent copies are only available for Raindrop Pro accounts.
+func (c *Client) GetPermanentCopyURL(ctx context.Context, dropID int64) (string, error) {
+	url := fmt.Sprintf("%s/raindrop/%d/cache", c.baseURL, dropID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.setAuthHeader(req)
+
+	// Stop at the redirect, so the archived file location can be downloaded without the API credentials
+	noRedirectClient := *c.httpClient
+	noRedirectClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	resp, err := c.doWithClient(&noRedirectClient, req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return "", newAPIError(resp)
+	}
+
+	location, err := resp.Location()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrNoRedirect, err)
+	}
+
+	return location.String(), nil
+}
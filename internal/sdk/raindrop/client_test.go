@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -18,6 +19,13 @@ const (
 	testAPIKey = "test-api-key"
 )
 
+// testRetryPolicy keeps retries fast in tests
+var testRetryPolicy = raindrop.RetryPolicy{
+	MaxRetries: 2,
+	BaseDelay:  time.Millisecond,
+	MaxDelay:   10 * time.Millisecond,
+}
+
 // Helper function to load testdata
 func loadTestData(t *testing.T, filePath string) []byte {
 	t.Helper()
@@ -32,6 +40,7 @@ func setupTestClient(t *testing.T, server *httptest.Server) *raindrop.Client {
 		raindrop.WithAPIKey(testAPIKey),
 		raindrop.WithHTTPClient(server.Client()),
 		raindrop.WithBaseURL(server.URL),
+		raindrop.WithRetryPolicy(testRetryPolicy),
 	)
 
 	require.NoError(t, err, "error creating raindrop client")
@@ -112,6 +121,24 @@ func TestNewClient(t *testing.T) {
 func TestGetImagesDropsFromCollection(t *testing.T) {
 	t.Parallel()
 
+	mockData := loadTestData(t, "testdata/get_raindrops_response_success.json")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "type:image", r.URL.Query().Get("search"))
+		_, _ = w.Write(mockData)
+	}))
+	defer server.Close()
+
+	client := setupTestClient(t, server)
+
+	imageDrops, err := client.GetImagesDropsFromCollection(context.Background(), 123, 0) //nolint:staticcheck // The deprecated method is still supported
+	require.NoError(t, err)
+	assert.NotEmpty(t, imageDrops.Items)
+}
+
+func TestGetDropsFromCollection(t *testing.T) {
+	t.Parallel()
+
 	t.Run("Success", func(t *testing.T) {
 		t.Parallel()
 		mockData := loadTestData(t, "testdata/get_raindrops_response_success.json")
@@ -133,7 +160,7 @@ func TestGetImagesDropsFromCollection(t *testing.T) {
 		client := setupTestClient(t, server)
 
 		// Call the method
-		imageDrops, err := client.GetImagesDropsFromCollection(context.Background(), 123, 1)
+		imageDrops, err := client.GetDropsFromCollection(context.Background(), 123, 1, raindrop.ListOptions{Search: raindrop.NewSearch().Type(raindrop.DropTypeImage)})
 		require.NoError(t, err)
 
 		// Check the returned result
@@ -143,6 +170,24 @@ func TestGetImagesDropsFromCollection(t *testing.T) {
 		assert.False(t, imageDrops.HasMore) // Since the total count matches the returned items
 	})
 
+	t.Run("WithSearch_SendsTheSearchQuery", func(t *testing.T) {
+		t.Parallel()
+		mockData := loadTestData(t, "testdata/get_raindrops_response_success.json")
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "type:image #reaction site:giphy.com", r.URL.Query().Get("search"))
+
+			_, _ = w.Write(mockData)
+		}))
+		defer server.Close()
+
+		client := setupTestClient(t, server)
+
+		search := raindrop.NewSearch().Type(raindrop.DropTypeImage).Tags("reaction").Domain("giphy.com")
+		_, err := client.GetDropsFromCollection(context.Background(), 123, 0, raindrop.ListOptions{Search: search})
+		require.NoError(t, err)
+	})
+
 	t.Run("Internal Server Error", func(t *testing.T) {
 		t.Parallel()
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -155,7 +200,7 @@ func TestGetImagesDropsFromCollection(t *testing.T) {
 		client := setupTestClient(t, server)
 
 		// Call the method expecting an error due to non-200 status code
-		_, err := client.GetImagesDropsFromCollection(context.Background(), 123, 1)
+		_, err := client.GetDropsFromCollection(context.Background(), 123, 1, raindrop.ListOptions{})
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "unexpected status code: 500")
 	})
@@ -174,7 +219,7 @@ func TestGetImagesDropsFromCollection(t *testing.T) {
 		client := setupTestClient(t, server)
 
 		// Call the method expecting a JSON decoding error
-		_, err := client.GetImagesDropsFromCollection(context.Background(), 123, 1)
+		_, err := client.GetDropsFromCollection(context.Background(), 123, 1, raindrop.ListOptions{})
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to decode response")
 	})
@@ -245,3 +290,182 @@ func TestGetCollectionByID(t *testing.T) {
 		assert.Contains(t, err.Error(), "failed to decode response")
 	})
 }
+
+func TestGetUser(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		mockData := loadTestData(t, "testdata/get_user_response_success.json")
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/user", r.URL.Path)
+			assert.Equal(t, fmt.Sprintf("Bearer %s", testAPIKey), r.Header.Get("Authorization"))
+
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(mockData)
+		}))
+		defer server.Close()
+
+		client := setupTestClient(t, server)
+
+		user, err := client.GetUser(context.Background())
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(32), user.ID)
+		assert.Equal(t, "Jane Doe", user.FullName)
+		assert.True(t, user.Pro)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer server.Close()
+
+		client := setupTestClient(t, server)
+
+		_, err := client.GetUser(context.Background())
+		assert.ErrorIs(t, err, raindrop.ErrUnauthorized)
+	})
+}
+
+func TestGetRootCollections(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		mockData := loadTestData(t, "testdata/get_root_collections_response_success.json")
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/collections", r.URL.Path)
+			assert.Equal(t, fmt.Sprintf("Bearer %s", testAPIKey), r.Header.Get("Authorization"))
+
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(mockData)
+		}))
+		defer server.Close()
+
+		client := setupTestClient(t, server)
+
+		collections, err := client.GetRootCollections(context.Background())
+		require.NoError(t, err)
+
+		require.Len(t, collections, 2)
+		assert.Equal(t, "Images", collections[0].Title)
+		assert.Equal(t, 0, collections[0].Parent.ID)
+		assert.Equal(t, "Wallpapers", collections[1].Title)
+	})
+
+	t.Run("Invalid JSON Response", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{invalid-json}`))
+		}))
+		defer server.Close()
+
+		client := setupTestClient(t, server)
+
+		_, err := client.GetRootCollections(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to decode response")
+	})
+}
+
+func TestGetChildCollections(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		mockData := loadTestData(t, "testdata/get_child_collections_response_success.json")
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/collections/childrens", r.URL.Path)
+			assert.Equal(t, fmt.Sprintf("Bearer %s", testAPIKey), r.Header.Get("Authorization"))
+
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(mockData)
+		}))
+		defer server.Close()
+
+		client := setupTestClient(t, server)
+
+		collections, err := client.GetChildCollections(context.Background())
+		require.NoError(t, err)
+
+		require.Len(t, collections, 2)
+		assert.Equal(t, "Reaction GIFs", collections[0].Title)
+		assert.Equal(t, 47919682, collections[0].Parent.ID)
+		assert.Equal(t, int64(47919695), collections[1].ID)
+		assert.Equal(t, 47919690, collections[1].Parent.ID)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer server.Close()
+
+		client := setupTestClient(t, server)
+
+		_, err := client.GetChildCollections(context.Background())
+		assert.ErrorIs(t, err, raindrop.ErrUnauthorized)
+	})
+}
+
+func TestGetPermanentCopyURL(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/raindrop/859330230/cache", r.URL.Path)
+			assert.Equal(t, fmt.Sprintf("Bearer %s", testAPIKey), r.Header.Get("Authorization"))
+
+			http.Redirect(w, r, "https://s3.example.com/cache/859330230.jpg?signature=abc", http.StatusTemporaryRedirect)
+		}))
+		defer server.Close()
+
+		client := setupTestClient(t, server)
+
+		url, err := client.GetPermanentCopyURL(context.Background(), 859330230)
+		require.NoError(t, err)
+
+		assert.Equal(t, "https://s3.example.com/cache/859330230.jpg?signature=abc", url)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		client := setupTestClient(t, server)
+
+		_, err := client.GetPermanentCopyURL(context.Background(), 123)
+		assert.ErrorIs(t, err, raindrop.ErrNotFound)
+	})
+
+	t.Run("Without Redirect", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusFound)
+		}))
+		defer server.Close()
+
+		client := setupTestClient(t, server)
+
+		_, err := client.GetPermanentCopyURL(context.Background(), 123)
+		assert.ErrorIs(t, err, raindrop.ErrNoRedirect)
+	})
+}
@@ -1,10 +1,22 @@
 // package raindrop provides an SDK to interact with the Raindrop API.
 // An API key is required to use this SDK. Check the official [Raindrop API documentation](https://developer.raindrop.io/v1/authentication/token) for more information.
-// To simplify the usage a "test token" is used instead a full OAuth2 flow.
+// To simplify the usage a "test token" can be used instead of the full OAuth2 flow. Apps authorized with OAuth2,
+// with [Authorize] for example, use [WithTokenSource] instead, which refreshes the tokens when they expire.
+// Rate limited (429) and server error responses are retried according to a [RetryPolicy], which can be changed with [WithRetryPolicy].
+// The requests and rate limit waits are reported to the [Observer] registered with [WithObserver], ex: to export metrics.
+// The drops of a collection can be listed page by page, or with a [DropIterator] that fetches the pages as needed.
 // Example Usage:
 //
 //	client, err := raindrop.NewClient(raindrop.WithAPIKey("test-api-key"))
 //	if err != nil {
 //		log.Fatalf("error creating client: %v", err)
 //	}
+//
+//	it := client.Drops(ctx, collectionID, raindrop.ListOptions{Sort: raindrop.SortOldest})
+//	for it.Next() {
+//		fmt.Println(it.Drop().Title)
+//	}
+//	if err := it.Err(); err != nil {
+//		log.Fatalf("error listing drops: %v", err)
+//	}
 package raindrop
@@ -0,0 +1,101 @@
+package raindrop
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrNotFound     = errors.New("not found")
+	ErrRateLimited  = errors.New("rate limited")
+)
+
+// RateLimit holds the rate limit information returned by the Raindrop API in the X-RateLimit-* headers
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// APIError is returned when the Raindrop API responds with an unexpected status code.
+// It can be matched against ErrUnauthorized, ErrNotFound and ErrRateLimited using errors.Is.
+type APIError struct {
+	StatusCode int
+	// Message is the errorMessage field of the response body, or the raw body if it is not JSON
+	Message   string
+	URL       string
+	RateLimit RateLimit
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("unexpected status code: %d, url: %s", e.StatusCode, e.URL)
+	}
+	return fmt.Sprintf("unexpected status code: %d, url: %s, response: %s", e.StatusCode, e.URL, e.Message)
+}
+
+// Is reports whether the error matches one of the sentinel errors of this package
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// errorResponse is the body returned by the Raindrop API on errors
+type errorResponse struct {
+	Result       bool   `json:"result"`
+	Error        string `json:"error"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// newAPIError builds an APIError from a response, consuming its body
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RateLimit:  parseRateLimit(resp.Header),
+	}
+
+	if resp.Request != nil {
+		apiErr.URL = resp.Request.URL.String()
+	}
+
+	respBody, _ := io.ReadAll(resp.Body)
+
+	var body errorResponse
+	if err := json.Unmarshal(respBody, &body); err == nil && (body.ErrorMessage != "" || body.Error != "") {
+		apiErr.Message = body.ErrorMessage
+		if apiErr.Message == "" {
+			apiErr.Message = body.Error
+		}
+	} else {
+		apiErr.Message = string(respBody)
+	}
+
+	return apiErr
+}
+
+// parseRateLimit reads the X-RateLimit-* headers. Missing or invalid values are left empty.
+func parseRateLimit(header http.Header) RateLimit {
+	var rateLimit RateLimit
+
+	rateLimit.Limit, _ = strconv.Atoi(header.Get(headerRateLimitLimit))
+	rateLimit.Remaining, _ = strconv.Atoi(header.Get(headerRateLimitRemaining))
+
+	if reset, err := strconv.ParseInt(header.Get(headerRateLimitReset), 10, 64); err == nil {
+		rateLimit.Reset = time.Unix(reset, 0)
+	}
+
+	return rateLimit
+}
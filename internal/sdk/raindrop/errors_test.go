@@ -0,0 +1,84 @@
+package raindrop_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+func TestAPIError(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-RateLimit-Limit", "120")
+			w.Header().Set("X-RateLimit-Remaining", "119")
+			w.Header().Set("X-RateLimit-Reset", "1586528100")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"result":false,"error":"invalid_token","errorMessage":"Invalid token"}`))
+		}))
+		defer server.Close()
+
+		client := setupTestClient(t, server)
+
+		_, err := client.GetCollectionByID(context.Background(), 123)
+		require.Error(t, err)
+
+		assert.ErrorIs(t, err, raindrop.ErrUnauthorized)
+		assert.NotErrorIs(t, err, raindrop.ErrNotFound)
+
+		var apiErr *raindrop.APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+		assert.Equal(t, "Invalid token", apiErr.Message)
+		assert.Equal(t, server.URL+"/collection/123", apiErr.URL)
+		assert.Equal(t, 120, apiErr.RateLimit.Limit)
+		assert.Equal(t, 119, apiErr.RateLimit.Remaining)
+		assert.Equal(t, int64(1586528100), apiErr.RateLimit.Reset.Unix())
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`not found`))
+		}))
+		defer server.Close()
+
+		client := setupTestClient(t, server)
+
+		_, err := client.GetDropsFromCollection(context.Background(), 123, 0, raindrop.ListOptions{})
+		require.Error(t, err)
+
+		assert.ErrorIs(t, err, raindrop.ErrNotFound)
+
+		var apiErr *raindrop.APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, "not found", apiErr.Message)
+	})
+
+	t.Run("RateLimited", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		client := setupTestClient(t, server)
+
+		_, err := client.GetCollectionByID(context.Background(), 123)
+		require.Error(t, err)
+
+		assert.ErrorIs(t, err, raindrop.ErrRateLimited)
+	})
+}
@@ -0,0 +1,158 @@
+package raindrop
+
+import (
+	"context"
+	"fmt"
+)
+
+// MaxPerPage is the maximum number of drops per page allowed by the Raindrop API, used by default
+const MaxPerPage = 50
+
+// Sort is the order in which drops are listed
+type Sort string
+
+const (
+	// SortNewest lists the newest drops first. This is the API default.
+	SortNewest Sort = "-created"
+	// SortOldest lists the oldest drops first
+	SortOldest Sort = "created"
+	// SortTitle lists drops by title, in alphabetical order
+	SortTitle Sort = "title"
+	// SortTitleDesc lists drops by title, in reverse alphabetical order
+	SortTitleDesc Sort = "-title"
+	// SortDomain lists drops by the domain of their link, in alphabetical order
+	SortDomain Sort = "domain"
+	// SortDomainDesc lists drops by the domain of their link, in reverse alphabetical order
+	SortDomainDesc Sort = "-domain"
+	// SortManual lists drops in the order set manually in the Raindrop app
+	SortManual Sort = "-sort"
+)
+
+// ListOptions configures how drops are listed
+type ListOptions struct {
+	Search Search
+	// PerPage is the number of drops per page, up to MaxPerPage. Zero uses MaxPerPage.
+	PerPage int
+	// Sort is the order of the drops. Empty uses the API default.
+	Sort Sort
+}
+
+func (o ListOptions) perPage() (int, error) {
+	switch {
+	case o.PerPage == 0:
+		return MaxPerPage, nil
+	case o.PerPage < 0 || o.PerPage > MaxPerPage:
+		return 0, fmt.Errorf("%w: %d, must be between 1 and %d", ErrInvalidPerPage, o.PerPage, MaxPerPage)
+	}
+	return o.PerPage, nil
+}
+
+// PageFetcher retrieves a page of the drops of a collection. It is implemented by Client.
+type PageFetcher interface {
+	GetDropsFromCollection(ctx context.Context, collectionID int, page int, opts ListOptions) (*DropsPage, error)
+}
+
+// DropIterator iterates over all the drops of a collection, fetching the pages as they are needed.
+// Drops returned twice, as it happens when drops are added while paging, are skipped.
+//
+//	it := client.Drops(ctx, collectionID, raindrop.ListOptions{})
+//	for it.Next() {
+//		drop := it.Drop()
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+//
+// A DropIterator is not safe for concurrent use.
+type DropIterator struct {
+	ctx          context.Context
+	fetcher      PageFetcher
+	collectionID int
+	opts         ListOptions
+
+	// page is the index of the last fetched page, -1 before the first one
+	page    int
+	items   []Drop
+	hasMore bool
+	count   int
+	current Drop
+	seen    map[int64]struct{}
+	err     error
+}
+
+// NewDropIterator returns an iterator over the drops of a collection, fetched with the given fetcher
+func NewDropIterator(ctx context.Context, fetcher PageFetcher, collectionID int, opts ListOptions) *DropIterator {
+	return &DropIterator{
+		ctx:          ctx,
+		fetcher:      fetcher,
+		collectionID: collectionID,
+		opts:         opts,
+		page:         -1,
+		hasMore:      true,
+		seen:         make(map[int64]struct{}),
+	}
+}
+
+// Next advances to the next drop, fetching the next page if needed.
+// It returns false when there are no more drops or an error occurred, which is reported by Err.
+func (it *DropIterator) Next() bool {
+	for {
+		if it.err != nil {
+			return false
+		}
+
+		for len(it.items) > 0 {
+			drop := it.items[0]
+			it.items = it.items[1:]
+
+			if _, ok := it.seen[drop.ID]; ok {
+				continue
+			}
+			it.seen[drop.ID] = struct{}{}
+
+			it.current = drop
+			return true
+		}
+
+		if !it.hasMore {
+			return false
+		}
+
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+
+		drops, err := it.fetcher.GetDropsFromCollection(it.ctx, it.collectionID, it.page+1, it.opts)
+		if err != nil {
+			it.err = fmt.Errorf("failed to get page %d: %w", it.page+1, err)
+			return false
+		}
+
+		it.page++
+		it.items = drops.Items
+		it.hasMore = drops.HasMore
+		it.count = drops.Count
+	}
+}
+
+// Drop returns the current drop. It must only be called after Next returned true.
+func (it *DropIterator) Drop() Drop {
+	return it.current
+}
+
+// Page returns the index of the page of the current drop, starting at 0
+func (it *DropIterator) Page() int {
+	return it.page
+}
+
+// Count returns the total number of drops reported by Raindrop with the last page, or 0 before the first page is fetched.
+// It may change while paging, if drops are added or removed in the meantime.
+func (it *DropIterator) Count() int {
+	return it.count
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *DropIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,191 @@
+package raindrop_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+// pagingServer serves a collection of total drops, with IDs starting at 1, paginated according to the request
+type pagingServer struct {
+	*httptest.Server
+	requests atomic.Int32
+}
+
+func setupPagingServer(t *testing.T, total int, handle func(w http.ResponseWriter, r *http.Request) bool) *pagingServer {
+	t.Helper()
+
+	s := &pagingServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		if handle != nil && handle(w, r) {
+			return
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("perpage"))
+
+		resp := raindrop.GetRaindropsResponse{Result: true, Count: total, Items: []raindrop.Drop{}}
+		for id := page*perPage + 1; id <= min((page+1)*perPage, total); id++ {
+			resp.Items = append(resp.Items, raindrop.Drop{ID: int64(id)})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// collectIDs consumes the iterator, returning the IDs of the drops
+func collectIDs(it *raindrop.DropIterator) []int64 {
+	var ids []int64
+	for it.Next() {
+		ids = append(ids, it.Drop().ID)
+	}
+	return ids
+}
+
+func TestDropIterator(t *testing.T) {
+	t.Parallel()
+
+	t.Run("IteratesOverAllPages", func(t *testing.T) {
+		t.Parallel()
+
+		server := setupPagingServer(t, 5, nil)
+		client := setupTestClient(t, server.Server)
+
+		it := client.Drops(context.Background(), 123, raindrop.ListOptions{PerPage: 2})
+
+		assert.Equal(t, 0, it.Count())
+		assert.Equal(t, []int64{1, 2, 3, 4, 5}, collectIDs(it))
+		require.NoError(t, it.Err())
+		assert.Equal(t, 2, it.Page())
+		assert.Equal(t, 5, it.Count())
+		assert.Equal(t, int32(3), server.requests.Load())
+	})
+
+	t.Run("WithFullLastPage_DoesNotRequestAnEmptyPage", func(t *testing.T) {
+		t.Parallel()
+
+		server := setupPagingServer(t, 4, nil)
+		client := setupTestClient(t, server.Server)
+
+		it := client.Drops(context.Background(), 123, raindrop.ListOptions{PerPage: 2})
+
+		assert.Equal(t, []int64{1, 2, 3, 4}, collectIDs(it))
+		require.NoError(t, it.Err())
+		assert.Equal(t, int32(2), server.requests.Load())
+	})
+
+	t.Run("WithEmptyCollection_ReturnsNoDrops", func(t *testing.T) {
+		t.Parallel()
+
+		server := setupPagingServer(t, 0, nil)
+		client := setupTestClient(t, server.Server)
+
+		it := client.Drops(context.Background(), 123, raindrop.ListOptions{})
+
+		assert.Empty(t, collectIDs(it))
+		require.NoError(t, it.Err())
+		assert.Equal(t, int32(1), server.requests.Load())
+	})
+
+	t.Run("SendsSortAndSearch", func(t *testing.T) {
+		t.Parallel()
+
+		server := setupPagingServer(t, 1, func(w http.ResponseWriter, r *http.Request) bool {
+			assert.Equal(t, "created", r.URL.Query().Get("sort"))
+			assert.Equal(t, "type:image", r.URL.Query().Get("search"))
+			assert.Equal(t, "50", r.URL.Query().Get("perpage"))
+			return false
+		})
+		client := setupTestClient(t, server.Server)
+
+		it := client.Drops(context.Background(), 123, raindrop.ListOptions{
+			Search: raindrop.NewSearch().Type(raindrop.DropTypeImage),
+			Sort:   raindrop.SortOldest,
+		})
+
+		assert.Equal(t, []int64{1}, collectIDs(it))
+		require.NoError(t, it.Err())
+	})
+
+	t.Run("SkipsDuplicateDrops", func(t *testing.T) {
+		t.Parallel()
+
+		// A drop added while paging shifts the following pages, so the last drop of a page is returned again
+		server := setupPagingServer(t, 4, func(w http.ResponseWriter, r *http.Request) bool {
+			if r.URL.Query().Get("page") != "1" {
+				return false
+			}
+			_ = json.NewEncoder(w).Encode(raindrop.GetRaindropsResponse{
+				Count: 4,
+				Items: []raindrop.Drop{{ID: 2}, {ID: 3}},
+			})
+			return true
+		})
+		client := setupTestClient(t, server.Server)
+
+		it := client.Drops(context.Background(), 123, raindrop.ListOptions{PerPage: 2})
+
+		assert.Equal(t, []int64{1, 2, 3}, collectIDs(it))
+		require.NoError(t, it.Err())
+	})
+
+	t.Run("StopsOnPageError", func(t *testing.T) {
+		t.Parallel()
+
+		server := setupPagingServer(t, 5, func(w http.ResponseWriter, r *http.Request) bool {
+			if r.URL.Query().Get("page") == "1" {
+				w.WriteHeader(http.StatusNotFound)
+				return true
+			}
+			return false
+		})
+		client := setupTestClient(t, server.Server)
+
+		it := client.Drops(context.Background(), 123, raindrop.ListOptions{PerPage: 2})
+
+		assert.Equal(t, []int64{1, 2}, collectIDs(it))
+		assert.ErrorIs(t, it.Err(), raindrop.ErrNotFound)
+		assert.ErrorContains(t, it.Err(), "failed to get page 1")
+		assert.False(t, it.Next())
+	})
+
+	t.Run("WithInvalidPageSize_ReturnsError", func(t *testing.T) {
+		t.Parallel()
+
+		server := setupPagingServer(t, 5, nil)
+		client := setupTestClient(t, server.Server)
+
+		it := client.Drops(context.Background(), 123, raindrop.ListOptions{PerPage: 51})
+
+		assert.False(t, it.Next())
+		assert.ErrorIs(t, it.Err(), raindrop.ErrInvalidPerPage)
+		assert.Equal(t, int32(0), server.requests.Load())
+	})
+
+	t.Run("WithCancelledContext_ReturnsError", func(t *testing.T) {
+		t.Parallel()
+
+		server := setupPagingServer(t, 5, nil)
+		client := setupTestClient(t, server.Server)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		it := client.Drops(ctx, 123, raindrop.ListOptions{})
+
+		assert.False(t, it.Next())
+		assert.ErrorIs(t, it.Err(), context.Canceled)
+	})
+}
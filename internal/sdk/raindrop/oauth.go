@@ -0,0 +1,167 @@
+package raindrop
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/oauth2"
+)
+
+// OAuth2Endpoint is the OAuth2 endpoint of Raindrop, used to authorize the apps registered in the Raindrop settings
+var OAuth2Endpoint = oauth2.Endpoint{
+	AuthURL:   "https://raindrop.io/oauth/authorize",
+	TokenURL:  "https://raindrop.io/oauth/access_token",
+	AuthStyle: oauth2.AuthStyleInParams,
+}
+
+var (
+	ErrAuthorizationDenied = errors.New("authorization denied")
+	ErrInvalidOAuthState   = errors.New("invalid OAuth2 state")
+	ErrInvalidRedirectURL  = errors.New("invalid redirect URL")
+)
+
+// NewOAuth2Config returns the OAuth2 configuration of a Raindrop app.
+// The redirect URL must match the one registered for the app.
+func NewOAuth2Config(clientID, clientSecret, redirectURL string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Endpoint:     OAuth2Endpoint,
+	}
+}
+
+// WithTokenSource authenticates the requests with OAuth2 tokens instead of an API key.
+// The tokens are refreshed by the token source when they expire, ex: the one returned by oauth2.Config.TokenSource.
+func WithTokenSource(ts oauth2.TokenSource) Option {
+	return func(c *Client) {
+		c.tokenSource = ts
+	}
+}
+
+// Authorize runs the OAuth2 authorization code flow with a loopback redirect.
+// It listens on the host of the redirect URL, which must be a loopback address, and calls open with the URL
+// the user must visit to authorize the app, ex: to print it or open a browser. Once Raindrop redirects
+// back with the authorization code, it is exchanged for a token.
+// Requests with another state, ex: forged by a local page, are rejected without ending the flow.
+// A redirect URL with port 0 listens on a random port, which is useful for tests.
+func Authorize(ctx context.Context, cfg *oauth2.Config, open func(authURL string) error) (*oauth2.Token, error) {
+	redirectURL, err := url.Parse(cfg.RedirectURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRedirectURL, err)
+	}
+	if !isLoopback(redirectURL.Hostname()) {
+		return nil, fmt.Errorf("%w: %q is not a loopback address", ErrInvalidRedirectURL, redirectURL.Hostname())
+	}
+
+	listener, err := net.Listen("tcp", redirectURL.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for the OAuth2 redirect: %w", err)
+	}
+	defer listener.Close()
+
+	// Use the port picked by the system, when the redirect URL has none
+	redirectURL.Host = net.JoinHostPort(redirectURL.Hostname(), fmt.Sprint(listener.Addr().(*net.TCPAddr).Port))
+	cfg = &oauth2.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		Endpoint:     cfg.Endpoint,
+		RedirectURL:  redirectURL.String(),
+		Scopes:       cfg.Scopes,
+	}
+
+	state, err := randomState()
+	if err != nil {
+		return nil, err
+	}
+
+	type callback struct {
+		code string
+		err  error
+	}
+	callbacks := make(chan callback, 1)
+
+	callbackPath := redirectURL.Path
+	if callbackPath == "" {
+		callbackPath = "/"
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(callbackPath, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		// The request was not sent by the authorization server, so the flow waits for the real redirect
+		if query.Get("state") != state {
+			http.Error(w, "Authorization failed: "+ErrInvalidOAuthState.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var result callback
+		switch {
+		case query.Get("error") != "":
+			result.err = fmt.Errorf("%w: %s", ErrAuthorizationDenied, query.Get("error"))
+		case query.Get("code") == "":
+			result.err = fmt.Errorf("%w: no authorization code", ErrAuthorizationDenied)
+		default:
+			result.code = query.Get("code")
+		}
+
+		if result.err != nil {
+			http.Error(w, "Authorization failed: "+result.err.Error(), http.StatusBadRequest)
+		} else {
+			_, _ = fmt.Fprintln(w, "Authorization complete, you can close this window.")
+		}
+
+		select {
+		case callbacks <- result:
+		default:
+		}
+	})
+
+	server := &http.Server{Handler: mux} // #nosec
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+
+	if err := open(cfg.AuthCodeURL(state)); err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-callbacks:
+		if result.err != nil {
+			return nil, result.err
+		}
+
+		token, err := cfg.Exchange(ctx, result.code)
+		if err != nil {
+			return nil, fmt.Errorf("failed to exchange the authorization code: %w", err)
+		}
+		return token, nil
+	}
+}
+
+// isLoopback checks if a host only accepts connections from the local machine
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// randomState returns a random value, that protects the redirect from forged requests
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate the OAuth2 state: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
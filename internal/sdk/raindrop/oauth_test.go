@@ -0,0 +1,215 @@
+package raindrop_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+// setupOAuthServer starts a stand-in for the Raindrop OAuth2 and API endpoints.
+// The authorization endpoint redirects back with the given query, to which the state is added.
+// The token endpoint issues "access-1" for the authorization code "code-1", and "access-2" when refreshing "refresh-1".
+func setupOAuthServer(t *testing.T, redirectQuery url.Values) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth/authorize":
+			query := url.Values{"state": {r.URL.Query().Get("state")}}
+			for key, values := range redirectQuery {
+				query[key] = values
+			}
+			http.Redirect(w, r, r.URL.Query().Get("redirect_uri")+"?"+query.Encode(), http.StatusFound)
+		case "/oauth/access_token":
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "client-id", r.PostForm.Get("client_id"))
+			assert.Equal(t, "client-secret", r.PostForm.Get("client_secret"))
+
+			token := map[string]any{"token_type": "Bearer", "refresh_token": "refresh-1", "expires_in": 3600}
+			switch {
+			case r.PostForm.Get("grant_type") == "authorization_code" && r.PostForm.Get("code") == "code-1":
+				token["access_token"] = "access-1"
+			case r.PostForm.Get("grant_type") == "refresh_token" && r.PostForm.Get("refresh_token") == "refresh-1":
+				token["access_token"] = "access-2"
+			default:
+				http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(token)
+		case "/rest/v1/user":
+			if r.Header.Get("Authorization") != "Bearer access-2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"result": true, "user": {"_id": 32, "fullName": "Jane Doe"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func setupOAuthConfig(server *httptest.Server) *oauth2.Config {
+	cfg := raindrop.NewOAuth2Config("client-id", "client-secret", "http://127.0.0.1:0/callback")
+	cfg.Endpoint.AuthURL = server.URL + "/oauth/authorize"
+	cfg.Endpoint.TokenURL = server.URL + "/oauth/access_token"
+	return cfg
+}
+
+// visit follows the authorization URL, like the browser of the user
+func visit(authURL string) error {
+	resp, err := http.Get(authURL) // #nosec
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func TestAuthorize(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+
+		server := setupOAuthServer(t, url.Values{"code": {"code-1"}})
+
+		token, err := raindrop.Authorize(context.Background(), setupOAuthConfig(server), visit)
+		require.NoError(t, err)
+
+		assert.Equal(t, "access-1", token.AccessToken)
+		assert.Equal(t, "refresh-1", token.RefreshToken)
+		assert.True(t, token.Expiry.After(time.Now()))
+	})
+
+	t.Run("Denied", func(t *testing.T) {
+		t.Parallel()
+
+		server := setupOAuthServer(t, url.Values{"error": {"access_denied"}})
+
+		_, err := raindrop.Authorize(context.Background(), setupOAuthConfig(server), visit)
+		assert.ErrorIs(t, err, raindrop.ErrAuthorizationDenied)
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		server := setupOAuthServer(t, nil)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		// The user never visits the URL
+		_, err := raindrop.Authorize(ctx, setupOAuthConfig(server), func(string) error {
+			cancel()
+			return nil
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestAuthorize_Redirect(t *testing.T) {
+	t.Parallel()
+
+	// forgeThenVisit sends a request with another state to the redirect URL, before following the authorization URL
+	forgeThenVisit := func(authURL string) error {
+		u, err := url.Parse(authURL)
+		if err != nil {
+			return err
+		}
+
+		resp, err := http.Get(u.Query().Get("redirect_uri") + "?code=forged&state=forged") // #nosec
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			return fmt.Errorf("unexpected status for the forged request: %s", resp.Status)
+		}
+
+		return visit(authURL)
+	}
+
+	tests := []struct {
+		name        string
+		redirectURL string
+		open        func(authURL string) error
+		expectedErr error
+	}{
+		{
+			name:        "WithoutPath",
+			redirectURL: "http://127.0.0.1:0",
+			open:        visit,
+		},
+		{
+			name:        "WithInvalidStateThenValidCallback",
+			redirectURL: "http://127.0.0.1:0/callback",
+			open:        forgeThenVisit,
+		},
+		{
+			name:        "WithAllInterfaces",
+			redirectURL: "http://0.0.0.0:0/callback",
+			open:        visit,
+			expectedErr: raindrop.ErrInvalidRedirectURL,
+		},
+		{
+			name:        "WithRemoteHost",
+			redirectURL: "http://example.com/callback",
+			open:        visit,
+			expectedErr: raindrop.ErrInvalidRedirectURL,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := setupOAuthServer(t, url.Values{"code": {"code-1"}})
+			cfg := setupOAuthConfig(server)
+			cfg.RedirectURL = tt.redirectURL
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			token, err := raindrop.Authorize(ctx, cfg, tt.open)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "access-1", token.AccessToken)
+		})
+	}
+}
+
+func TestWithTokenSource(t *testing.T) {
+	t.Parallel()
+
+	server := setupOAuthServer(t, nil)
+	cfg := setupOAuthConfig(server)
+
+	expired := &oauth2.Token{AccessToken: "access-1", RefreshToken: "refresh-1", Expiry: time.Now().Add(-time.Minute)}
+
+	client, err := raindrop.NewClient(
+		raindrop.WithTokenSource(cfg.TokenSource(context.Background(), expired)),
+		raindrop.WithBaseURL(server.URL+"/rest/v1"),
+	)
+	require.NoError(t, err)
+
+	// The expired token is refreshed by the transport
+	user, err := client.GetUser(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Jane Doe", user.FullName)
+}
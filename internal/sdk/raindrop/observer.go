@@ -0,0 +1,37 @@
+package raindrop
+
+import (
+	"net/http"
+	"time"
+)
+
+// Observer is notified of the requests sent by the client, ex: to export metrics.
+// Requests are sent in parallel by the callers of the client, so implementations must be safe for concurrent use.
+type Observer interface {
+	// OnRequest is called after each attempt of a request, including the retried ones,
+	// with the status code of the response, or 0 when no response was received
+	OnRequest(req *http.Request, statusCode int)
+	// OnRateLimitWait is called before the client waits for the rate limit to reset, or for the delay of a 429 response
+	OnRateLimitWait(wait time.Duration)
+}
+
+// NopObserver implements Observer with methods that do nothing.
+// It can be embedded to only implement some of the callbacks.
+type NopObserver struct{}
+
+func (NopObserver) OnRequest(*http.Request, int)  {}
+func (NopObserver) OnRateLimitWait(time.Duration) {}
+
+// WithObserver registers an observer of the requests sent by the client. It can be used several times.
+func WithObserver(observer Observer) Option {
+	return func(c *Client) {
+		c.observers = append(c.observers, observer)
+	}
+}
+
+// notify calls fn with each observer
+func (c *Client) notify(fn func(Observer)) {
+	for _, observer := range c.observers {
+		fn(observer)
+	}
+}
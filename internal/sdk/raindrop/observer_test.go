@@ -0,0 +1,124 @@
+package raindrop_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+// recordingObserver records the requests and rate limit waits reported by the client
+type recordingObserver struct {
+	mu       sync.Mutex
+	statuses []int
+	paths    []string
+	waits    []time.Duration
+}
+
+func (o *recordingObserver) OnRequest(req *http.Request, statusCode int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.statuses = append(o.statuses, statusCode)
+	o.paths = append(o.paths, req.URL.Path)
+}
+
+func (o *recordingObserver) OnRateLimitWait(wait time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.waits = append(o.waits, wait)
+}
+
+func TestClient_Observer(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ReportsEachAttempt", func(t *testing.T) {
+		t.Parallel()
+		mockData := loadTestData(t, "testdata/get_collection_response_success.json")
+
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if attempts.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write(mockData)
+		}))
+		defer server.Close()
+
+		observer := &recordingObserver{}
+		client, err := raindrop.NewClient(
+			raindrop.WithAPIKey(testAPIKey),
+			raindrop.WithBaseURL(server.URL),
+			raindrop.WithRetryPolicy(testRetryPolicy),
+			raindrop.WithObserver(observer),
+		)
+		require.NoError(t, err)
+
+		_, err = client.GetCollectionByID(context.Background(), 47919682)
+		require.NoError(t, err)
+
+		assert.Equal(t, []int{http.StatusServiceUnavailable, http.StatusOK}, observer.statuses)
+		assert.Equal(t, []string{"/collection/47919682", "/collection/47919682"}, observer.paths)
+		assert.Empty(t, observer.waits, "server errors are not rate limit waits")
+	})
+
+	t.Run("ReportsRateLimitWaits", func(t *testing.T) {
+		t.Parallel()
+		mockData := loadTestData(t, "testdata/get_collection_response_success.json")
+
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if attempts.Add(1) == 1 {
+				w.Header().Set("Retry-After", "30")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			_, _ = w.Write(mockData)
+		}))
+		defer server.Close()
+
+		observer := &recordingObserver{}
+		client, err := raindrop.NewClient(
+			raindrop.WithAPIKey(testAPIKey),
+			raindrop.WithBaseURL(server.URL),
+			raindrop.WithRetryPolicy(raindrop.RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}),
+			raindrop.WithObserver(observer),
+		)
+		require.NoError(t, err)
+
+		_, err = client.GetCollectionByID(context.Background(), 47919682)
+		require.NoError(t, err)
+
+		assert.Equal(t, []int{http.StatusTooManyRequests, http.StatusOK}, observer.statuses)
+		assert.Equal(t, []time.Duration{10 * time.Millisecond}, observer.waits, "the wait is capped by the retry policy")
+	})
+
+	t.Run("ReportsNetworkErrors", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		server.Close()
+
+		observer := &recordingObserver{}
+		client, err := raindrop.NewClient(
+			raindrop.WithAPIKey(testAPIKey),
+			raindrop.WithBaseURL(server.URL),
+			raindrop.WithRetryPolicy(raindrop.RetryPolicy{}),
+			raindrop.WithObserver(observer),
+		)
+		require.NoError(t, err)
+
+		_, err = client.GetCollectionByID(context.Background(), 47919682)
+		require.Error(t, err)
+
+		assert.Equal(t, []int{0}, observer.statuses)
+	})
+}
@@ -0,0 +1,244 @@
+package raindrop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	headerRetryAfter         = "Retry-After"
+	headerRateLimitLimit     = "X-RateLimit-Limit"
+	headerRateLimitRemaining = "X-RateLimit-Remaining"
+	headerRateLimitReset     = "X-RateLimit-Reset"
+)
+
+// RetryPolicy defines how failed requests are retried.
+// Requests that fail with a 429, a 5xx status code or a transient network error are retried
+// with a jittered exponential backoff, unless the server tells how long to wait.
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of retries after the first attempt. Zero disables retries.
+	MaxRetries int
+	// BaseDelay is the delay before the first retry. It doubles on each following retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between two attempts, including delays requested by the server.
+	// Zero means no cap.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy used when none is configured.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 5,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   time.Minute,
+}
+
+// RetryError is returned when a request still fails after all the retries allowed by the RetryPolicy.
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("giving up after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// WithRetryPolicy sets the policy used to retry failed requests
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// backoff returns the jittered exponential delay for the given retry attempt (starting at 0).
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << attempt
+	switch {
+	case p.BaseDelay <= 0:
+		delay = p.MaxDelay
+	case delay>>attempt != p.BaseDelay:
+		// The delay overflowed, so it is only limited by the cap
+		delay = math.MaxInt64
+	}
+	delay = p.capDelay(delay)
+
+	// Equal jitter: wait between half and the full delay, to spread concurrent retries.
+	half := delay / 2
+	return half + time.Duration(rand.Int64N(int64(half)+1)) // #nosec G404 -- jitter does not need a secure source
+}
+
+// capDelay limits a delay to the MaxDelay of the policy, if set
+func (p RetryPolicy) capDelay(delay time.Duration) time.Duration {
+	if p.MaxDelay > 0 {
+		return min(delay, p.MaxDelay)
+	}
+	return delay
+}
+
+// do sends the request, retrying it according to the client retry policy.
+// A response is returned only when its status code is not retryable; the caller must close its body.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	return c.doWithClient(c.httpClient, req)
+}
+
+// doWithClient is like do, but sends the request with the given HTTP client.
+func (c *Client) doWithClient(httpClient *http.Client, req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		if err := c.waitForRateLimit(ctx); err != nil {
+			return nil, err
+		}
+
+		var lastErr error
+		var delay time.Duration
+
+		resp, err := httpClient.Do(req.Clone(ctx))
+
+		statusCode := 0
+		if err == nil {
+			statusCode = resp.StatusCode
+		}
+		c.notify(func(o Observer) { o.OnRequest(req, statusCode) })
+
+		switch {
+		case err != nil:
+			if !isTransientError(err) {
+				return nil, fmt.Errorf("failed to perform request: %w", err)
+			}
+			lastErr = fmt.Errorf("failed to perform request: %w", err)
+		default:
+			c.trackRateLimit(resp.Header)
+			if !isRetryableStatus(resp.StatusCode) {
+				return resp, nil
+			}
+
+			lastErr = newAPIError(resp)
+			resp.Body.Close()
+			delay = serverDelay(resp.Header, time.Now())
+		}
+
+		if c.retryPolicy.MaxRetries == 0 {
+			return nil, lastErr
+		}
+
+		if attempt >= c.retryPolicy.MaxRetries {
+			return nil, &RetryError{Attempts: attempt + 1, Err: lastErr}
+		}
+
+		if delay <= 0 {
+			delay = c.retryPolicy.backoff(attempt)
+		}
+		delay = c.retryPolicy.capDelay(delay)
+
+		if statusCode == http.StatusTooManyRequests {
+			c.notify(func(o Observer) { o.OnRateLimitWait(delay) })
+		}
+
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// trackRateLimit records when the rate limit window resets, once the remaining requests are exhausted.
+func (c *Client) trackRateLimit(header http.Header) {
+	if header.Get(headerRateLimitRemaining) != "0" {
+		return
+	}
+
+	rateLimit := parseRateLimit(header)
+	if rateLimit.Reset.IsZero() {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rateLimitResetAt = rateLimit.Reset
+}
+
+// waitForRateLimit blocks until the rate limit window resets, if it was exhausted by a previous request.
+func (c *Client) waitForRateLimit(ctx context.Context) error {
+	c.mu.Lock()
+	wait := time.Until(c.rateLimitResetAt)
+	c.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	wait = c.retryPolicy.capDelay(wait)
+	c.notify(func(o Observer) { o.OnRateLimitWait(wait) })
+
+	return sleep(ctx, wait)
+}
+
+// serverDelay returns how long the server asked to wait before retrying, based on the
+// Retry-After header (in seconds or as an HTTP date) or the X-RateLimit-Reset epoch.
+// It returns zero if no delay was requested.
+func serverDelay(header http.Header, now time.Time) time.Duration {
+	if retryAfter := header.Get(headerRetryAfter); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+
+		if date, err := http.ParseTime(retryAfter); err == nil {
+			return date.Sub(now)
+		}
+	}
+
+	if header.Get(headerRateLimitRemaining) == "0" {
+		if reset := parseRateLimit(header).Reset; !reset.IsZero() {
+			return reset.Sub(now)
+		}
+	}
+
+	return 0
+}
+
+// isRetryableStatus reports whether a request that returned the status code may succeed if retried.
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// isTransientError reports whether a network error is likely to be temporary.
+func isTransientError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// sleep waits for the given duration or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
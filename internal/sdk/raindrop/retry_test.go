@@ -0,0 +1,224 @@
+package raindrop_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+func TestClient_Retry(t *testing.T) {
+	t.Parallel()
+
+	t.Run("RetriesServerErrors_UntilSuccess", func(t *testing.T) {
+		t.Parallel()
+		mockData := loadTestData(t, "testdata/get_collection_response_success.json")
+
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if attempts.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write(mockData)
+		}))
+		defer server.Close()
+
+		client := setupTestClient(t, server)
+
+		collection, err := client.GetCollectionByID(context.Background(), 47919682)
+		require.NoError(t, err)
+
+		assert.Equal(t, "Images", collection.Title)
+		assert.Equal(t, int32(3), attempts.Load())
+	})
+
+	t.Run("GivesUp_AfterMaxRetries", func(t *testing.T) {
+		t.Parallel()
+
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		client := setupTestClient(t, server)
+
+		_, err := client.GetDropsFromCollection(context.Background(), 123, 0, raindrop.ListOptions{})
+		require.Error(t, err)
+
+		var retryErr *raindrop.RetryError
+		require.ErrorAs(t, err, &retryErr)
+		assert.Equal(t, 3, retryErr.Attempts)
+		assert.Equal(t, int32(3), attempts.Load())
+	})
+
+	t.Run("DoesNotRetry_ClientErrors", func(t *testing.T) {
+		t.Parallel()
+
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		client := setupTestClient(t, server)
+
+		_, err := client.GetCollectionByID(context.Background(), 123)
+		require.Error(t, err)
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+
+	t.Run("WithRetriesDisabled_ReturnsFirstError", func(t *testing.T) {
+		t.Parallel()
+
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		client, err := raindrop.NewClient(
+			raindrop.WithAPIKey(testAPIKey),
+			raindrop.WithBaseURL(server.URL),
+			raindrop.WithRetryPolicy(raindrop.RetryPolicy{}),
+		)
+		require.NoError(t, err)
+
+		_, err = client.GetCollectionByID(context.Background(), 123)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unexpected status code: 502")
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+
+	t.Run("HonoursRetryAfter", func(t *testing.T) {
+		t.Parallel()
+		mockData := loadTestData(t, "testdata/get_collection_response_success.json")
+
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if attempts.Add(1) == 1 {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			_, _ = w.Write(mockData)
+		}))
+		defer server.Close()
+
+		client, err := raindrop.NewClient(
+			raindrop.WithAPIKey(testAPIKey),
+			raindrop.WithBaseURL(server.URL),
+			raindrop.WithRetryPolicy(raindrop.RetryPolicy{
+				MaxRetries: 1,
+				BaseDelay:  time.Millisecond,
+				MaxDelay:   5 * time.Second,
+			}),
+		)
+		require.NoError(t, err)
+
+		start := time.Now()
+		_, err = client.GetCollectionByID(context.Background(), 47919682)
+		require.NoError(t, err)
+
+		assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	})
+
+	t.Run("WithoutMaxDelay_BacksOffWithoutCap", func(t *testing.T) {
+		t.Parallel()
+		mockData := loadTestData(t, "testdata/get_collection_response_success.json")
+
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch attempts.Add(1) {
+			case 1:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case 2:
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+			default:
+				_, _ = w.Write(mockData)
+			}
+		}))
+		defer server.Close()
+
+		// A zero MaxDelay does not cap the backoff nor the delay requested by the server
+		client, err := raindrop.NewClient(
+			raindrop.WithAPIKey(testAPIKey),
+			raindrop.WithBaseURL(server.URL),
+			raindrop.WithRetryPolicy(raindrop.RetryPolicy{MaxRetries: 2, BaseDelay: 200 * time.Millisecond}),
+		)
+		require.NoError(t, err)
+
+		start := time.Now()
+		_, err = client.GetCollectionByID(context.Background(), 47919682)
+		require.NoError(t, err)
+
+		assert.Equal(t, int32(3), attempts.Load())
+		assert.GreaterOrEqual(t, time.Since(start), 1100*time.Millisecond)
+	})
+
+	t.Run("WaitsForRateLimitReset", func(t *testing.T) {
+		t.Parallel()
+		mockData := loadTestData(t, "testdata/get_collection_response_success.json")
+
+		var lastRequest atomic.Int64
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lastRequest.Store(time.Now().UnixNano())
+			reset := time.Now().Add(2 * time.Second).Unix()
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+			_, _ = w.Write(mockData)
+		}))
+		defer server.Close()
+
+		client, err := raindrop.NewClient(
+			raindrop.WithAPIKey(testAPIKey),
+			raindrop.WithBaseURL(server.URL),
+			raindrop.WithRetryPolicy(raindrop.RetryPolicy{MaxDelay: 5 * time.Second}),
+		)
+		require.NoError(t, err)
+
+		_, err = client.GetCollectionByID(context.Background(), 47919682)
+		require.NoError(t, err)
+		first := lastRequest.Load()
+
+		_, err = client.GetCollectionByID(context.Background(), 47919682)
+		require.NoError(t, err)
+
+		assert.GreaterOrEqual(t, time.Duration(lastRequest.Load()-first), 900*time.Millisecond)
+	})
+
+	t.Run("StopsWaiting_WhenContextIsCancelled", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		client, err := raindrop.NewClient(
+			raindrop.WithAPIKey(testAPIKey),
+			raindrop.WithBaseURL(server.URL),
+		)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err = client.GetCollectionByID(ctx, 123)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
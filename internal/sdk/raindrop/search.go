@@ -0,0 +1,113 @@
+package raindrop
+
+import (
+	"strings"
+	"time"
+)
+
+// searchDateLayout is the date format of the Raindrop search operators
+const searchDateLayout = "2006-01-02"
+
+// Search is a Raindrop search query, built from typed filters that are compiled into
+// the Raindrop search syntax (see https://help.raindrop.io/using-search).
+// The zero value matches every drop. Search values are immutable: each method returns a modified copy.
+type Search struct {
+	dropType DropType
+	query    string
+	tags     []string
+	domain   string
+	since    time.Time
+	until    time.Time
+}
+
+// NewSearch returns an empty search
+func NewSearch() Search {
+	return Search{}
+}
+
+// Type restricts the search to drops of the given type
+func (s Search) Type(dropType DropType) Search {
+	s.dropType = dropType
+	return s
+}
+
+// Query adds a raw Raindrop search query, combined with the other filters
+func (s Search) Query(query string) Search {
+	s.query = strings.TrimSpace(query)
+	return s
+}
+
+// Tags restricts the search to drops with all the given tags
+func (s Search) Tags(tags ...string) Search {
+	s.tags = append(s.tags[:len(s.tags):len(s.tags)], tags...)
+	return s
+}
+
+// Domain restricts the search to drops whose link is on the given domain
+func (s Search) Domain(domain string) Search {
+	s.domain = strings.TrimSpace(domain)
+	return s
+}
+
+// Since restricts the search to drops created after the given date
+func (s Search) Since(date time.Time) Search {
+	s.since = date
+	return s
+}
+
+// Until restricts the search to drops created before the given date
+func (s Search) Until(date time.Time) Search {
+	s.until = date
+	return s
+}
+
+// IsZero reports whether the search has no filter
+func (s Search) IsZero() bool {
+	return s.String() == ""
+}
+
+// String returns the search in the Raindrop search syntax
+func (s Search) String() string {
+	var terms []string
+
+	if s.dropType != "" {
+		terms = append(terms, "type:"+string(s.dropType))
+	}
+
+	if s.query != "" {
+		terms = append(terms, s.query)
+	}
+
+	for _, tag := range s.tags {
+		if tag = strings.TrimSpace(strings.TrimPrefix(tag, "#")); tag != "" {
+			terms = append(terms, "#"+quoteTerm(tag))
+		}
+	}
+
+	if s.domain != "" {
+		terms = append(terms, "site:"+s.domain)
+	}
+
+	if !s.since.IsZero() {
+		terms = append(terms, "created:>"+s.since.Format(searchDateLayout))
+	}
+
+	if !s.until.IsZero() {
+		terms = append(terms, "created:<"+s.until.Format(searchDateLayout))
+	}
+
+	return strings.Join(terms, " ")
+}
+
+// ParseSearchDate parses a date in the YYYY-MM-DD format used by the search operators
+func ParseSearchDate(value string) (time.Time, error) {
+	return time.Parse(searchDateLayout, value)
+}
+
+// quoteTerm wraps the term in quotes when it contains spaces, so it is matched as a whole
+func quoteTerm(term string) string {
+	if strings.ContainsAny(term, " \t") {
+		return `"` + strings.ReplaceAll(term, `"`, "") + `"`
+	}
+	return term
+}
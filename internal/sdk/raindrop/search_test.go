@@ -0,0 +1,66 @@
+package raindrop_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
+)
+
+func TestSearch_String(t *testing.T) {
+	t.Parallel()
+
+	since := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		search   raindrop.Search
+		expected string
+	}{
+		{"Empty", raindrop.NewSearch(), ""},
+		{"Query", raindrop.NewSearch().Query("  funny cats "), "funny cats"},
+		{"Tags", raindrop.NewSearch().Tags("reaction", "#meme", "big mood", ""), `#reaction #meme #"big mood"`},
+		{"Domain", raindrop.NewSearch().Domain("imgur.com"), "site:imgur.com"},
+		{"Dates", raindrop.NewSearch().Since(since).Until(until), "created:>2024-01-15 created:<2024-06-01"},
+		{
+			"Combined",
+			raindrop.NewSearch().Type(raindrop.DropTypeVideo).Query("cat").Tags("reaction").Domain("giphy.com").Since(since),
+			"type:video cat #reaction site:giphy.com created:>2024-01-15",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, tt.search.String())
+			assert.Equal(t, tt.expected == "", tt.search.IsZero())
+		})
+	}
+}
+
+func TestSearch_IsImmutable(t *testing.T) {
+	t.Parallel()
+
+	base := raindrop.NewSearch().Tags("a")
+	first := base.Tags("b")
+	second := base.Tags("c")
+
+	assert.Equal(t, "#a", base.String())
+	assert.Equal(t, "#a #b", first.String())
+	assert.Equal(t, "#a #c", second.String())
+}
+
+func TestParseSearchDate(t *testing.T) {
+	t.Parallel()
+
+	date, err := raindrop.ParseSearchDate("2024-01-15")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), date)
+
+	_, err = raindrop.ParseSearchDate("15/01/2024")
+	assert.Error(t, err)
+}
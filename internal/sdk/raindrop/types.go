@@ -1,15 +1,24 @@
 package raindrop
 
 import (
+	"encoding/json"
 	"strings"
 	"time"
 )
 
-type ImageDrops struct {
+// DropsPage is a page of drops returned by the Raindrop API
+type DropsPage struct {
 	Items   []Drop
 	HasMore bool
+	// Count is the total number of drops matching the request, across all pages
+	Count int
 }
 
+// ImageDrops is a page of image drops.
+//
+// Deprecated: Use DropsPage.
+type ImageDrops = DropsPage
+
 type GetRaindropsResponse struct {
 	Result bool   `json:"result"`
 	Items  []Drop `json:"items"`
@@ -22,20 +31,54 @@ type Drop struct {
 	Title        string        `json:"title"`
 	Excerpt      string        `json:"excerpt"`
 	Note         string        `json:"note"`
-	Type         string        `json:"type"`
+	Type         DropType      `json:"type"`
 	Cover        string        `json:"cover"`
-	Media        []string      `json:"media"`
+	Media        []MediaItem   `json:"media"`
 	Tags         []string      `json:"tags"`
 	Created      time.Time     `json:"created"`
+	Domain       string        `json:"domain"`
 	Collection   CollectionRef `json:"collection"`
 	LastUpdate   string        `json:"lastUpdate"`
 	CollectionID int64         `json:"collectionId"`
 }
 
+// DropType is the kind of content bookmarked by a drop
+type DropType string
+
+const (
+	DropTypeLink     DropType = "link"
+	DropTypeArticle  DropType = "article"
+	DropTypeImage    DropType = "image"
+	DropTypeVideo    DropType = "video"
+	DropTypeDocument DropType = "document"
+	DropTypeAudio    DropType = "audio"
+)
+
 func (d Drop) GetFileLink() string {
 	return d.Cover
 }
 
+// GetMediaLinks returns the links of all media of the drop, starting with the cover, without duplicates
+func (d Drop) GetMediaLinks() []string {
+	seen := make(map[string]struct{}, len(d.Media)+1)
+	links := make([]string, 0, len(d.Media)+1)
+
+	add := func(link string) {
+		if _, ok := seen[link]; ok || link == "" {
+			return
+		}
+		seen[link] = struct{}{}
+		links = append(links, link)
+	}
+
+	add(d.Cover)
+	for _, m := range d.Media {
+		add(m.Link)
+	}
+
+	return links
+}
+
 func (d Drop) GetName() string {
 	return strings.ReplaceAll(d.Title, " ", "_")
 }
@@ -44,6 +87,24 @@ func (d Drop) GetDescription() string {
 	return d.Note
 }
 
+// MediaItem is a media (cover) of a drop.
+// The Raindrop API returns media as objects, but a plain link string is also accepted.
+type MediaItem struct {
+	Link string `json:"link"`
+	Type string `json:"type,omitempty"`
+}
+
+func (m *MediaItem) UnmarshalJSON(data []byte) error {
+	var link string
+	if err := json.Unmarshal(data, &link); err == nil {
+		m.Link = link
+		return nil
+	}
+
+	type mediaItem MediaItem
+	return json.Unmarshal(data, (*mediaItem)(m))
+}
+
 type CollectionRef struct {
 	Ref string `json:"$ref"`
 	ID  int64  `json:"$id"`
@@ -55,6 +116,12 @@ type GetCollectionResponse struct {
 	Item   CollectionItem `json:"item"`
 }
 
+// GetCollectionsResponse is the response of the endpoints listing collections
+type GetCollectionsResponse struct {
+	Result bool             `json:"result"`
+	Items  []CollectionItem `json:"items"`
+}
+
 type CollectionItem struct {
 	ID          int64    `json:"_id"`
 	Title       string   `json:"title"`
@@ -93,3 +160,17 @@ type Access struct {
 	Root      bool `json:"root"`
 	Draggable bool `json:"draggable"`
 }
+
+// GetUserResponse is the response of the endpoint returning the authenticated user
+type GetUserResponse struct {
+	Result bool `json:"result"`
+	User   User `json:"user"`
+}
+
+// User is a Raindrop account
+type User struct {
+	ID       int64  `json:"_id"`
+	FullName string `json:"fullName"`
+	Email    string `json:"email"`
+	Pro      bool   `json:"pro"`
+}
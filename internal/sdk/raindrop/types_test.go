@@ -1,9 +1,11 @@
 package raindrop_test
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/brpaz/raindrop-images-dl/internal/sdk/raindrop"
 )
@@ -39,3 +41,36 @@ func TestDrop_GetFileLink(t *testing.T) {
 
 	assert.Equal(t, mockDrop.Cover, mockDrop.GetFileLink())
 }
+
+func TestDrop_GetMediaLinks(t *testing.T) {
+	t.Parallel()
+
+	drop := raindrop.Drop{
+		Cover: "https://test.com/cover.jpg",
+		Media: []raindrop.MediaItem{
+			{Link: "https://test.com/cover.jpg"},
+			{Link: "https://test.com/image2.jpg"},
+			{Link: ""},
+			{Link: "https://test.com/image3.jpg"},
+		},
+	}
+
+	assert.Equal(t, []string{
+		"https://test.com/cover.jpg",
+		"https://test.com/image2.jpg",
+		"https://test.com/image3.jpg",
+	}, drop.GetMediaLinks())
+}
+
+func TestMediaItem_UnmarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	var drop raindrop.Drop
+	err := json.Unmarshal([]byte(`{"media":[{"link":"https://test.com/a.jpg","type":"image"},"https://test.com/b.jpg"]}`), &drop)
+	require.NoError(t, err)
+
+	assert.Equal(t, []raindrop.MediaItem{
+		{Link: "https://test.com/a.jpg", Type: "image"},
+		{Link: "https://test.com/b.jpg"},
+	}, drop.Media)
+}